@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"iter"
+	"sync"
+)
+
+// Prefetch ranges seq in a background goroutine, reading up to n values ahead
+// of the consumer. When the consumer stops early the producer is told to stop
+// and waited for, so no goroutine outlives the range. A panic in seq is
+// re-raised in the consumer's goroutine
+func Prefetch[V any](seq iter.Seq[V], n int) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		values := make(chan V, max(n, 0))
+		done := make(chan struct{})
+		var panicked any
+
+		go func() {
+			defer close(values)
+			defer func() { panicked = recover() }()
+			for v := range seq {
+				select {
+				case values <- v:
+				case <-done:
+					return
+				}
+			}
+		}()
+		defer func() {
+			close(done)
+			for range values {
+			}
+		}()
+
+		for v := range values {
+			if !yield(v) {
+				return
+			}
+		}
+		if panicked != nil {
+			panic(panicked)
+		}
+	}
+}
+
+// ParallelMap applies f to the values of seq on workers goroutines and yields
+// the results in the order of the input. Results that complete ahead of their
+// turn are buffered; at most 2*workers values are in flight or buffered at a
+// time. When the consumer stops early, the range stops seq and returns once
+// the in-flight calls of f have finished. Values already taken from seq by
+// then are dropped: up to 2*workers+1 past the last one yielded, the extra
+// one being a value pulled but not yet handed to a worker
+func ParallelMap[V, R any](seq iter.Seq[V], workers int, f func(V) R) iter.Seq[R] {
+	type job struct {
+		i int
+		v V
+	}
+	type result struct {
+		i int
+		r R
+	}
+	return func(yield func(R) bool) {
+		workers := max(workers, 1)
+		jobs := make(chan job)
+		results := make(chan result)
+		slots := make(chan struct{}, 2*workers)
+		done := make(chan struct{})
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(jobs)
+			i := 0
+			for v := range seq {
+				select {
+				case slots <- struct{}{}:
+				case <-done:
+					return
+				}
+				select {
+				case jobs <- job{i, v}:
+				case <-done:
+					return
+				}
+				i++
+			}
+		}()
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := range jobs {
+					select {
+					case results <- result{j.i, f(j.v)}:
+					case <-done:
+						return
+					}
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(results)
+		}()
+		defer func() {
+			close(done)
+			for range results {
+			}
+		}()
+
+		pending := make(map[int]R)
+		next := 0
+		for res := range results {
+			pending[res.i] = res.r
+			for r, ok := pending[next]; ok; r, ok = pending[next] {
+				delete(pending, next)
+				next++
+				<-slots
+				if !yield(r) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// SafePull2 is iter.Pull2 whose next and stop may be called from several
+// goroutines: calls are serialized with a mutex, so they take turns rather
+// than run in parallel. As with iter.Pull2, stop may be called any number of
+// times and next returns false once the sequence is stopped or exhausted
+func SafePull2[K, V any](seq iter.Seq2[K, V]) (next func() (K, V, bool), stop func()) {
+	var mu sync.Mutex
+	pullNext, pullStop := iter.Pull2(seq)
+	next = func() (K, V, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		return pullNext()
+	}
+	stop = func() {
+		mu.Lock()
+		defer mu.Unlock()
+		pullStop()
+	}
+	return next, stop
+}
+
+// ForEachConcurrent calls f for every value of seq on workers goroutines,
+// in the manner of errgroup: the first error cancels the context passed to
+// the other calls, stops seq and is returned once all calls have returned.
+// Cancelling ctx stops the iteration the same way and returns ctx.Err()
+func ForEachConcurrent[V any](ctx context.Context, seq iter.Seq[V], workers int, f func(context.Context, V) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	values := make(chan V)
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range values {
+				if ctx.Err() != nil {
+					continue
+				}
+				if err := f(ctx, v); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+feed:
+	for v := range seq {
+		select {
+		case values <- v:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(values)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// MergeFair yields the values of all seqs as they become available: every
+// source is ranged in its own goroutine, so a slow source never holds up the
+// others. The order across sources is whatever order the values arrive in.
+// Stopping early stops every producer and waits for them
+func MergeFair[V any](seqs ...iter.Seq[V]) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		values := make(chan V)
+		done := make(chan struct{})
+
+		var wg sync.WaitGroup
+		for _, seq := range seqs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for v := range seq {
+					select {
+					case values <- v:
+					case <-done:
+						return
+					}
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(values)
+		}()
+		defer func() {
+			close(done)
+			for range values {
+			}
+		}()
+
+		for v := range values {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
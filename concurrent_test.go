@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// sleepy yields 0..n-1, sleeping d before each value like a slow I/O source
+func sleepy(n int, d time.Duration) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i := range n {
+			time.Sleep(d)
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+// checkNoLeak fails the test if more goroutines are running than before,
+// giving the ones that are winding down a moment to exit
+func checkNoLeak(t *testing.T, before int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Errorf("%d goroutines running, want %d", runtime.NumGoroutine(), before)
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPrefetch(t *testing.T) {
+	var got []int
+	for v := range Prefetch(slices.Values([]int{1, 2, 3, 4, 5}), 2) {
+		got = append(got, v)
+	}
+	if !slices.Equal(got, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("got %v, want [1 2 3 4 5]", got)
+	}
+}
+
+func TestPrefetchEarlyStop(t *testing.T) {
+	before := runtime.NumGoroutine()
+	stopped := false
+	source := func(yield func(int) bool) {
+		defer func() { stopped = true }()
+		for i := 0; ; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+	for v := range Prefetch(source, 4) {
+		if v == 2 {
+			break
+		}
+	}
+	if !stopped {
+		t.Error("the source was not stopped when the range ended")
+	}
+	checkNoLeak(t, before)
+}
+
+func TestPrefetchPanic(t *testing.T) {
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Errorf("recovered %v, want the source's panic", r)
+		}
+	}()
+	source := func(yield func(int) bool) {
+		yield(1)
+		panic("boom")
+	}
+	for range Prefetch(source, 1) {
+	}
+	t.Error("the range ended without a panic")
+}
+
+// BenchmarkPrefetch reads a source that sleeps per element while the
+// consumer does as much work again; prefetching overlaps the two
+func BenchmarkPrefetch(b *testing.B) {
+	const (
+		n     = 20
+		delay = 200 * time.Microsecond
+	)
+	consume := func(seq iter.Seq[int]) {
+		for range seq {
+			time.Sleep(delay)
+		}
+	}
+	b.Run("direct", func(b *testing.B) {
+		for range b.N {
+			consume(sleepy(n, delay))
+		}
+	})
+	b.Run("prefetch", func(b *testing.B) {
+		for range b.N {
+			consume(Prefetch(sleepy(n, delay), 4))
+		}
+	})
+}
+
+func TestParallelMapOrder(t *testing.T) {
+	input := make([]int, 100)
+	for i := range input {
+		input[i] = i
+	}
+	square := func(v int) int {
+		time.Sleep(time.Duration(rand.IntN(500)) * time.Microsecond)
+		return v * v
+	}
+
+	var got []int
+	for r := range ParallelMap(slices.Values(input), 8, square) {
+		got = append(got, r)
+	}
+	if len(got) != len(input) {
+		t.Fatalf("got %d results, want %d", len(got), len(input))
+	}
+	for i, r := range got {
+		if r != i*i {
+			t.Fatalf("result %d = %d, want %d", i, r, i*i)
+		}
+	}
+}
+
+func TestParallelMapEarlyStop(t *testing.T) {
+	before := runtime.NumGoroutine()
+	pulled := 0
+	source := func(yield func(int) bool) {
+		for i := 0; ; i++ {
+			pulled++
+			if !yield(i) {
+				return
+			}
+		}
+	}
+	for r := range ParallelMap(source, 4, func(v int) int { return v }) {
+		if r == 10 {
+			break
+		}
+	}
+	// At most 2*workers+1 values may get ahead of the consumer
+	if max := 11 + 2*4 + 1; pulled > max {
+		t.Errorf("pulled %d values for 11 results, want at most %d", pulled, max)
+	}
+	checkNoLeak(t, before)
+}
+
+// TestSafePull2 hammers next from several goroutines; run with -race
+func TestSafePull2(t *testing.T) {
+	const n = 1000
+	values := make([]int, n)
+	for i := range values {
+		values[i] = i * i
+	}
+	next, stop := SafePull2(slices.All(values))
+	defer stop()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		seen = make(map[int]int)
+	)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, v, ok := next(); ok; i, v, ok = next() {
+				if v != i*i {
+					t.Errorf("got pair %d, %d", i, v)
+				}
+				mu.Lock()
+				seen[i]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != n {
+		t.Errorf("got %d distinct pairs, want %d", len(seen), n)
+	}
+	for i, count := range seen {
+		if count != 1 {
+			t.Errorf("pair %d pulled %d times, want once", i, count)
+		}
+	}
+}
+
+func TestSafePull2ConcurrentStop(t *testing.T) {
+	next, stop := SafePull2(slices.All([]int{1, 2, 3}))
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			next()
+		}()
+		go func() {
+			defer wg.Done()
+			stop()
+		}()
+	}
+	wg.Wait()
+	if _, _, ok := next(); ok {
+		t.Error("next returned a pair after stop")
+	}
+}
+
+func TestForEachConcurrentError(t *testing.T) {
+	errBad := errors.New("bad value")
+	var (
+		pulled    atomic.Int64
+		calls     atomic.Int64
+		cancelled atomic.Int64
+	)
+	infinite := func(yield func(int) bool) {
+		for i := 0; ; i++ {
+			pulled.Add(1)
+			if !yield(i) {
+				return
+			}
+		}
+	}
+	// Every call but the failing one waits for the cancellation
+	err := ForEachConcurrent(context.Background(), infinite, 4, func(ctx context.Context, v int) error {
+		calls.Add(1)
+		if v == 2 {
+			return errBad
+		}
+		select {
+		case <-ctx.Done():
+			cancelled.Add(1)
+		case <-time.After(5 * time.Second):
+		}
+		return nil
+	})
+
+	if !errors.Is(err, errBad) {
+		t.Errorf("got %v, want %v", err, errBad)
+	}
+	if c, n := cancelled.Load(), calls.Load(); c != n-1 {
+		t.Errorf("%d of the %d other calls saw the cancellation, want all", c, n-1)
+	}
+	if n := pulled.Load(); n > 1000 {
+		t.Errorf("pulled %d values, want the source stopped after the error", n)
+	}
+}
+
+func TestForEachConcurrentCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls atomic.Int64
+	err := ForEachConcurrent(ctx, slices.Values(make([]int, 100)), 2, func(context.Context, int) error {
+		if calls.Add(1) == 5 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	if n := calls.Load(); n >= 100 {
+		t.Errorf("f was called %d times, want the iteration stopped", n)
+	}
+}
+
+func TestMergeFair(t *testing.T) {
+	slow := func(yield func(int) bool) {
+		for v := range sleepy(3, 50*time.Millisecond) {
+			if !yield(100 + v) {
+				return
+			}
+		}
+	}
+	fast := slices.Values([]int{1, 2, 3, 4, 5})
+
+	got := slices.Collect(MergeFair(slow, fast))
+	if len(got) != 8 {
+		t.Fatalf("got %v, want all 8 values", got)
+	}
+	// The fast values must not wait behind the slow source's first sleep
+	if want := []int{1, 2, 3, 4, 5, 100, 101, 102}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestMergeFairEarlyStop(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for v := range MergeFair(sleepy(100, time.Millisecond), sleepy(100, 2*time.Millisecond)) {
+		if v == 3 {
+			break
+		}
+	}
+	checkNoLeak(t, before)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"iter"
+	"math"
+	"math/rand/v2"
+)
+
+const limit = 10
+
+// Distribution selects how RandomValuesGenerator draws its values
+type Distribution int
+
+const (
+	Uniform     Distribution = iota // rand.Float64 in [0, 1); All keeps its ints in [0, 100)
+	Normal                          // rand.NormFloat64, mean 0 and stddev 1 unless set with WithNormal
+	Exponential                     // rand.ExpFloat64, rate 1
+)
+
+// RandomValuesGenerator yields limit random values per range.
+// The zero value draws uniform values from the global source
+type RandomValuesGenerator struct {
+	rand  *rand.Rand // global source if nil
+	dist  Distribution
+	limit int // the limit constant if not positive
+
+	mean, stddev float64 // parameters of the Normal distribution
+}
+
+type GeneratorOption func(*RandomValuesGenerator)
+
+// WithSeed makes the generator draw from a PCG source seeded with seed, so
+// the values are reproducible
+func WithSeed(seed uint64) GeneratorOption {
+	return func(g *RandomValuesGenerator) {
+		g.rand = rand.New(rand.NewPCG(seed, seed))
+	}
+}
+
+// WithDistribution selects the distribution the values are drawn from
+func WithDistribution(d Distribution) GeneratorOption {
+	return func(g *RandomValuesGenerator) {
+		g.dist = d
+	}
+}
+
+// WithNormal selects the normal distribution with the given mean and stddev.
+// A zero stddev is taken as it is: every value is then the mean
+func WithNormal(mean, stddev float64) GeneratorOption {
+	return func(g *RandomValuesGenerator) {
+		g.dist, g.mean, g.stddev = Normal, mean, stddev
+	}
+}
+
+// WithLimit sets how many values a range yields; n <= 0 keeps the default
+func WithLimit(n int) GeneratorOption {
+	return func(g *RandomValuesGenerator) {
+		g.limit = n
+	}
+}
+
+func NewRandomValuesGenerator(opts ...GeneratorOption) RandomValuesGenerator {
+	g := RandomValuesGenerator{stddev: 1}
+	for _, opt := range opts {
+		opt(&g)
+	}
+	return g
+}
+
+// All returns iteration index and value pairs. Uniform values are ints in
+// [0, 100); the other distributions are rounded to the nearest int.
+// The sequence can be ranged any number of times; every range starts again
+// from index 0 and draws fresh random values
+func (g RandomValuesGenerator) All() iter.Seq2[int, int] {
+	if g.dist == Uniform {
+		if g.rand == nil {
+			return generate(g.n(), func() int { return rand.IntN(100) })
+		}
+		return generate(g.n(), func() int { return g.rand.IntN(100) })
+	}
+	next := g.float()
+	return generate(g.n(), func() int { return int(math.Round(next())) })
+}
+
+// Floats is All drawing float64 values from the configured distribution
+func (g RandomValuesGenerator) Floats() iter.Seq2[int, float64] {
+	return generate(g.n(), g.float())
+}
+
+// float returns the function drawing a float64 from the configured distribution
+func (g RandomValuesGenerator) float() func() float64 {
+	uniform, normal, exp := rand.Float64, rand.NormFloat64, rand.ExpFloat64
+	if g.rand != nil {
+		uniform, normal, exp = g.rand.Float64, g.rand.NormFloat64, g.rand.ExpFloat64
+	}
+	switch g.dist {
+	case Normal:
+		return func() float64 { return g.mean + g.stddev*normal() }
+	case Exponential:
+		return exp
+	default:
+		return uniform
+	}
+}
+
+func (g RandomValuesGenerator) n() int {
+	if g.limit <= 0 {
+		return limit
+	}
+	return g.limit
+}
+
+func generate[V any](n int, next func() V) iter.Seq2[int, V] {
+	return func(yield func(int, V) bool) {
+		for i := 0; i < n; i++ {
+			if !yield(i, next()) {
+				fmt.Println("Received stop")
+				return
+			}
+		}
+		fmt.Println("Limit reached")
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGeneratorDistributions(t *testing.T) {
+	tests := []struct {
+		dist   Distribution
+		floats []float64
+		ints   []int
+	}{
+		{Uniform, []float64{0.3402859786606234, 0.9099579380225021, 0.8287848564104272}, []int{99, 10, 86}},
+		{Normal, []float64{0.9063577669680134, 0.4439636476587333, -0.7832387716160727}, []int{1, 0, -1}},
+		{Exponential, []float64{0.8820355090390364, 0.14204458232165237, 1.125510852626812}, []int{1, 0, 1}},
+	}
+	for _, tt := range tests {
+		newGenerator := func() RandomValuesGenerator {
+			return NewRandomValuesGenerator(WithSeed(1), WithDistribution(tt.dist), WithLimit(3))
+		}
+		var (
+			floats []float64
+			ints   []int
+		)
+		captureStdout(t, func() {
+			for _, v := range newGenerator().Floats() {
+				floats = append(floats, v)
+			}
+			for _, v := range newGenerator().All() {
+				ints = append(ints, v)
+			}
+		})
+		if !slices.Equal(floats, tt.floats) {
+			t.Errorf("distribution %d: Floats = %v, want %v", tt.dist, floats, tt.floats)
+		}
+		if !slices.Equal(ints, tt.ints) {
+			t.Errorf("distribution %d: All = %v, want %v", tt.dist, ints, tt.ints)
+		}
+	}
+}
+
+func TestGeneratorWithNormal(t *testing.T) {
+	collect := func(opts ...GeneratorOption) []int {
+		var values []int
+		captureStdout(t, func() {
+			for _, v := range NewRandomValuesGenerator(opts...).All() {
+				values = append(values, v)
+			}
+		})
+		return values
+	}
+
+	want := []int{59, 54, 42}
+	if got := collect(WithSeed(1), WithLimit(3), WithNormal(50, 10)); !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// Selecting the distribution again keeps the parameters
+	if got := collect(WithSeed(1), WithLimit(3), WithNormal(50, 10), WithDistribution(Normal)); !slices.Equal(got, want) {
+		t.Errorf("after WithDistribution(Normal): got %v, want %v", got, want)
+	}
+	// A zero stddev is a constant distribution, not the default stddev of 1
+	if got := collect(WithSeed(1), WithLimit(3), WithNormal(50, 0)); !slices.Equal(got, []int{50, 50, 50}) {
+		t.Errorf("zero stddev: got %v, want [50 50 50]", got)
+	}
+}
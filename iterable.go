@@ -0,0 +1,30 @@
+package main
+
+import "iter"
+
+// Iterable is anything that can be ranged over as pairs through its All
+// method, the central abstraction of the generators and readers here.
+// The generator is an Iterable[int, int], the line readers are
+// Iterable[string, error], and so on
+type Iterable[K, V any] interface {
+	All() iter.Seq2[K, V]
+}
+
+var (
+	_ Iterable[int, int]        = RandomValuesGenerator{}
+	_ Iterable[string, error]   = FileReader{}
+	_ Iterable[string, error]   = LineReader{}
+	_ Iterable[string, error]   = StdinReader{}
+	_ Iterable[string, error]   = StringReader{}
+	_ Iterable[[]string, error] = CSVReader{}
+	_ Iterable[any, error]      = JSONArrayReader[any]{}
+)
+
+// Count ranges it once and returns the number of pairs it yielded
+func Count[K, V any](it Iterable[K, V]) int {
+	n := 0
+	for range it.All() {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// keys takes any Iterable, whatever its value type, and collects its keys
+func keys[K, V any](it Iterable[K, V]) []K {
+	var ks []K
+	for k := range it.All() {
+		ks = append(ks, k)
+	}
+	return ks
+}
+
+func TestIterable(t *testing.T) {
+	gen := NewRandomValuesGenerator(WithSeed(1), WithLimit(4))
+	file := NewFileReader(writeTestFile(t, "a\nb\nc\n"))
+
+	var (
+		genCount, fileCount int
+		genKeys             []int
+		fileKeys            []string
+	)
+	captureStdout(t, func() {
+		genCount, genKeys = Count(gen), keys(gen)
+	})
+	fileCount, fileKeys = Count(file), keys(file)
+
+	if genCount != 4 || !slices.Equal(genKeys, []int{0, 1, 2, 3}) {
+		t.Errorf("generator: Count = %d, keys %v; want 4 and [0 1 2 3]", genCount, genKeys)
+	}
+	if fileCount != 3 || !slices.Equal(fileKeys, []string{"a", "b", "c"}) {
+		t.Errorf("file: Count = %d, keys %q; want 3 and [a b c]", fileCount, fileKeys)
+	}
+}
@@ -1,70 +1,63 @@
 package main
 
 import (
-	"bufio"
 	"errors"
+	"flag"
 	"fmt"
-	"io"
 	"iter"
 	"maps"
-	"math/rand/v2"
 	"os"
 	"slices"
 	"strings"
 )
 
-const limit = 10
-
-type RandomValuesGenerator struct{}
+type config struct {
+	file  string
+	limit int
+	seed  uint64
+}
 
-// All returns iteration index and value pairs
-func (g RandomValuesGenerator) All() iter.Seq2[int, int] {
-	return func(yield func(int, int) bool) {
-		for i := 0; i < limit; i++ {
-			if !yield(i, rand.IntN(100)) {
-				fmt.Println("Received stop")
-				return
-			}
-		}
-		fmt.Println("Limit reached")
+// parseFlags reads the config from the command line arguments (without the program name)
+func parseFlags(args []string) (config, error) {
+	var cfg config
+	fs := flag.NewFlagSet("playground", flag.ContinueOnError)
+	fs.StringVar(&cfg.file, "file", "./dump.txt", "file read by the file exercises")
+	fs.IntVar(&cfg.limit, "limit", limit, "number of values yielded by the random generator")
+	fs.Uint64Var(&cfg.seed, "seed", 0, "seed for the random generator; 0 draws from the global source")
+	if err := fs.Parse(args); err != nil {
+		return config{}, err
 	}
+	return cfg, nil
 }
 
-type FileReader struct {
-	file string
+func (cfg config) generator() RandomValuesGenerator {
+	opts := []GeneratorOption{WithLimit(cfg.limit)}
+	if cfg.seed != 0 {
+		opts = append(opts, WithSeed(cfg.seed))
+	}
+	return NewRandomValuesGenerator(opts...)
 }
 
-func NewFileReader(file string) FileReader {
-	return FileReader{file: file}
+func main() {
+	cfg, err := parseFlags(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+	if err := run(cfg); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+		os.Exit(1)
+	}
 }
 
-func (r FileReader) All() iter.Seq2[string, error] {
-	return func(yield func(string, error) bool) {
-		file, err := os.Open(r.file)
-		if err != nil {
-			yield("", fmt.Errorf("open: %w", err))
-			return
-		}
-		defer file.Close()
-
-		reader := bufio.NewReader(file)
-		for {
-			line, _, err := reader.ReadLine()
-			if errors.Is(err, io.EOF) {
-				return
-			}
-			if err != nil {
-				yield("", fmt.Errorf("read line: %w", err))
-				return
-			}
-			if !yield(string(line), nil) {
-				return
-			}
-		}
+// run runs the exercises, returning an error instead of exiting so it stays testable
+func run(cfg config) error {
+	if _, err := os.Stat(cfg.file); err != nil {
+		return fmt.Errorf("input file: %w", err)
 	}
-}
 
-func main() {
 	{
 		fmt.Println("Exercise 1: Base iterator usage with slice")
 		sl := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
@@ -83,13 +76,17 @@ func main() {
 			fmt.Printf("%s: %s; ", k, v)
 		}
 		// Output: Apple: Unites States; Samsung: South Korea; Xiaomi: China;
+
+		fmt.Println("\nExercise 2.1: Print sorted map with Fprint")
+		Fprint(os.Stdout, SortedByKey(maps.All(m)), "; ")
+		// Output: Apple: Unites States; Samsung: South Korea; Xiaomi: China
 	}
 
 	fmt.Print("\n\n")
 
 	{
 		fmt.Println("Exercise 3: Custom iterator usage with range")
-		generator := RandomValuesGenerator{}
+		generator := cfg.generator()
 		for i, v := range generator.All() {
 			fmt.Printf("%d: %d; ", i, v)
 		}
@@ -100,7 +97,7 @@ func main() {
 
 	{
 		fmt.Println("Exercise 4: Custom iterator usage with iter.Pull2")
-		generator := RandomValuesGenerator{}
+		generator := cfg.generator()
 		next, stop := iter.Pull2(generator.All())
 		defer stop()
 		for i, v, ok := next(); ok; i, v, ok = next() {
@@ -119,7 +116,7 @@ func main() {
 
 	{
 		fmt.Println("Exercise 5: Custom iterator usage with iter.Pull2 and custom stop")
-		generator := RandomValuesGenerator{}
+		generator := cfg.generator()
 		next, stop := iter.Pull2(generator.All())
 
 		for i := 0; i < 5; i++ {
@@ -147,7 +144,7 @@ func main() {
 
 	{
 		fmt.Println("Exercise 6: Read file with iterator")
-		reader := NewFileReader("./dump.txt")
+		reader := NewFileReader(cfg.file)
 		next, stop := iter.Pull2(reader.All())
 		defer stop()
 
@@ -167,5 +164,66 @@ func main() {
 				Read line: Lorem ipsum dolor sit amet
 				Stop: Donec malesuada suscipit nulla, STOP HERE
 		*/
+
+		fmt.Println("\nExercise 6.1: Read file until STOP with LineScanner")
+		scanner := NewLineScanner(reader.All())
+		for line, err := range scanner.Until(func(line string) bool { return strings.Contains(line, "STOP") }) {
+			if err != nil {
+				fmt.Println("Error: " + err.Error())
+				continue
+			}
+			fmt.Println("Read line: " + line)
+		}
+		scanner.Stop() // No panic
+		/*
+			Output:
+				Read line: Lorem ipsum dolor sit amet
+				Read line: Donec malesuada suscipit nulla, STOP HERE
+		*/
+	}
+
+	fmt.Print("\n")
+
+	{
+		fmt.Println("Exercise 7: Range the same file reader twice")
+		reader := NewFileReader(cfg.file)
+		var first, second []string
+		for line := range reader.All() {
+			first = append(first, line)
+		}
+		for line := range reader.All() {
+			second = append(second, line)
+		}
+		fmt.Printf("%d lines, equal: %v\n", len(first), slices.Equal(first, second))
+		// Output: 5 lines, equal: true
+	}
+
+	fmt.Print("\n")
+
+	{
+		fmt.Println("Exercise 8: Read a line longer than the read buffer")
+		reader := NewStringReader(strings.Repeat("x", 10*1024) + "\nshort")
+		for line := range reader.All() {
+			fmt.Printf("%d bytes; ", len(line))
+		}
+		// Output: 10240 bytes; 5 bytes;
+	}
+
+	fmt.Print("\n\n")
+
+	{
+		fmt.Println("Exercise 9: Count any Iterable")
+		values := Count(cfg.generator())
+		lines := Count(NewFileReader(cfg.file))
+		fmt.Printf("%d values; %d lines;", values, lines)
+		/*
+			Output:
+				Limit reached
+				10 values; 5 lines;
+		*/
 	}
+
+	fmt.Print("\n")
+
+	return nil
 }
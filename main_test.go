@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		args []string
+		want config
+	}{
+		{nil, config{file: "./dump.txt", limit: limit}},
+		{[]string{"-file", "other.txt", "-limit", "3", "-seed", "42"}, config{file: "other.txt", limit: 3, seed: 42}},
+		{[]string{"-limit=0"}, config{file: "./dump.txt"}},
+	}
+	for _, tt := range tests {
+		got, err := parseFlags(tt.args)
+		if err != nil {
+			t.Errorf("parseFlags(%q): %v", tt.args, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseFlags(%q) = %+v, want %+v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestParseFlagsErrors(t *testing.T) {
+	if _, err := parseFlags([]string{"-h"}); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("-h: got %v, want flag.ErrHelp", err)
+	}
+	if _, err := parseFlags([]string{"-limit", "many"}); err == nil {
+		t.Error("a non-numeric -limit was accepted")
+	}
+	if _, err := parseFlags([]string{"-unknown"}); err == nil {
+		t.Error("an unknown flag was accepted")
+	}
+}
+
+func TestRunMissingFile(t *testing.T) {
+	cfg := config{file: filepath.Join(t.TempDir(), "missing.txt"), limit: limit}
+	out := captureStdout(t, func() {
+		err := run(cfg)
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("got %v, want an error wrapping fs.ErrNotExist", err)
+		}
+	})
+	if out != "" {
+		t.Errorf("run printed %q before failing, want nothing", out)
+	}
+}
+
+func TestRun(t *testing.T) {
+	cfg, err := parseFlags([]string{"-limit", "5", "-seed", "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := captureStdout(t, func() {
+		if err := run(cfg); err != nil {
+			t.Error(err)
+		}
+	})
+	// The seeded generator yields 99, 10, 86, 91, 28 first
+	if !strings.Contains(out, "0: 99; 1: 10; 2: 86; 3: 91; 4: 28; Limit reached") {
+		t.Errorf("run output doesn't show five seeded values:\n%s", out)
+	}
+	if !strings.Contains(out, "Stop: Donec malesuada suscipit nulla, STOP HERE") {
+		t.Errorf("run output doesn't show the file exercise:\n%s", out)
+	}
+}
@@ -0,0 +1,819 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"iter"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileError records a failed file operation together with the path it was
+// performed on, so callers can recover both via errors.As
+type FileError struct {
+	Path string
+	Op   string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return e.Op + " " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// ErrReadTimeout is reported when reading a line takes longer than the
+// duration set with WithReadTimeout
+var ErrReadTimeout = errors.New("read timeout")
+
+// lineConfig holds the options shared by the line readers
+type lineConfig struct {
+	readTimeout     time.Duration
+	keepLineEndings bool
+	bufferSize      int
+}
+
+type LineOption func(*lineConfig)
+
+// WithReadTimeout makes the reader yield an ErrReadTimeout error and stop if
+// reading a single line takes longer than d. Each line is then read in a
+// goroutine of its own, started only when the consumer asks for the line.
+// On a timeout the source (for FileReader, the file) is closed if it
+// implements io.Closer, to unblock the read. Otherwise that goroutine lives
+// on until its Read returns; the next range of a LineReader or StdinReader
+// waits for it and yields the line it read first
+func WithReadTimeout(d time.Duration) LineOption {
+	return func(c *lineConfig) {
+		c.readTimeout = d
+	}
+}
+
+// KeepLineEndings makes the reader yield lines with their original "\n" or
+// "\r\n" terminator, so the input can be reconstructed byte for byte
+func KeepLineEndings() LineOption {
+	return func(c *lineConfig) {
+		c.keepLineEndings = true
+	}
+}
+
+// WithBufferSize sets the size of the read buffer, bufio's default if not
+// positive. Lines longer than the buffer are still read whole
+func WithBufferSize(size int) LineOption {
+	return func(c *lineConfig) {
+		c.bufferSize = size
+	}
+}
+
+func newLineConfig(opts []LineOption) lineConfig {
+	var c lineConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+type LineReader struct {
+	src *lineSource
+	cfg lineConfig
+}
+
+func NewLineReader(r io.Reader, opts ...LineOption) LineReader {
+	cfg := newLineConfig(opts)
+	return LineReader{src: newLineSource(r, cfg), cfg: cfg}
+}
+
+// All returns the lines read from the underlying reader, or an error in place
+// of a line. Unlike FileReader.All it can't rewind its source, but all ranges
+// share one buffered reader: ranging it again continues with the line after
+// the last one the previous range yielded
+func (r LineReader) All() iter.Seq2[string, error] {
+	return bufferedSeq(r.src, r.cfg)
+}
+
+// stdin is the source shared by every StdinReader reading standard input, so
+// that successive ranges don't lose input buffered by an earlier one
+var stdin = sync.OnceValue(func() *lineSource {
+	return newLineSource(os.Stdin, lineConfig{})
+})
+
+// StdinReader reads lines from standard input, or from the reader it was
+// created with. The zero value reads standard input
+type StdinReader struct {
+	src *lineSource // the shared stdin source if nil
+	cfg lineConfig
+}
+
+// NewStdinReader returns a StdinReader reading r, or standard input if r is
+// nil. Standard input is buffered once for the whole program with the default
+// buffer size, whatever WithBufferSize says
+func NewStdinReader(r io.Reader, opts ...LineOption) StdinReader {
+	cfg := newLineConfig(opts)
+	if r == nil {
+		return StdinReader{src: stdin(), cfg: cfg}
+	}
+	return StdinReader{src: newLineSource(r, cfg), cfg: cfg}
+}
+
+// All returns the lines piped to the program, or an error in place of a line.
+// As with LineReader, ranging it again continues where the previous range
+// stopped
+func (r StdinReader) All() iter.Seq2[string, error] {
+	if r.src == nil {
+		return bufferedSeq(stdin(), r.cfg)
+	}
+	return bufferedSeq(r.src, r.cfg)
+}
+
+type StringReader struct {
+	s string
+}
+
+func NewStringReader(s string) StringReader {
+	return StringReader{s: s}
+}
+
+// All returns the lines of the string. As with FileReader, every range starts
+// again from the beginning
+func (r StringReader) All() iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		readerSeq(strings.NewReader(r.s), lineConfig{})(yield)
+	}
+}
+
+// readerSeq returns the lines read from r, or an error in place of a line,
+// buffering r afresh on every range
+func readerSeq(r io.Reader, cfg lineConfig) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		bufferedSeq(newLineSource(r, cfg), cfg)(yield)
+	}
+}
+
+// lineSource is the buffered reader the ranges of a line reader share
+type lineSource struct {
+	reader *bufio.Reader
+	closer io.Closer // closed to unblock a read that timed out, if not nil
+
+	// pending is a read that was still running when its range timed out. The
+	// next range waits for it and yields its line, rather than reading
+	// alongside it
+	pending chan lineResult
+}
+
+type lineResult struct {
+	line string
+	err  error
+}
+
+func newLineSource(r io.Reader, cfg lineConfig) *lineSource {
+	closer, _ := r.(io.Closer)
+	return &lineSource{reader: newBufReader(r, cfg), closer: closer}
+}
+
+func newBufReader(r io.Reader, cfg lineConfig) *bufio.Reader {
+	if cfg.bufferSize > 0 {
+		return bufio.NewReaderSize(r, cfg.bufferSize)
+	}
+	return bufio.NewReader(r)
+}
+
+// bufferedSeq is the single line splitting loop behind every line reader.
+// It reads from s without wrapping it again, so ranges sharing s pick up
+// where the previous one stopped. A line is only read once the consumer asks
+// for it, so a range that stops early leaves the rest for the next one
+func bufferedSeq(s *lineSource, cfg lineConfig) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for {
+			line, err := s.next(cfg)
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield("", fmt.Errorf("read line: %w", err))
+				return
+			}
+			if !yield(line, nil) {
+				return
+			}
+		}
+	}
+}
+
+// next reads the next line, giving up with ErrReadTimeout if that takes
+// longer than the read timeout. io.EOF means there are no more lines
+func (s *lineSource) next(cfg lineConfig) (string, error) {
+	if cfg.readTimeout <= 0 {
+		return readLine(s.reader, cfg.keepLineEndings)
+	}
+	read := s.pending
+	s.pending = nil
+	if read == nil {
+		read = make(chan lineResult, 1)
+		go func() {
+			line, err := readLine(s.reader, cfg.keepLineEndings)
+			read <- lineResult{line, err}
+		}()
+	}
+
+	timer := time.NewTimer(cfg.readTimeout)
+	defer timer.Stop()
+	select {
+	case res := <-read:
+		return res.line, res.err
+	case <-timer.C:
+		s.pending = read
+		if s.closer != nil {
+			s.closer.Close()
+		}
+		return "", ErrReadTimeout
+	}
+}
+
+// readLine reads one line from reader, without its line ending unless
+// keepLineEndings is set. An unterminated last line comes with a nil error,
+// and io.EOF only once there is nothing left
+func readLine(reader *bufio.Reader, keepLineEndings bool) (string, error) {
+	if keepLineEndings {
+		line, err := reader.ReadString('\n')
+		if line != "" && errors.Is(err, io.EOF) {
+			return line, nil
+		}
+		return line, err
+	}
+
+	line, isPrefix, err := reader.ReadLine()
+	if isPrefix {
+		// The line is longer than the buffer: ReadLine hands it out in
+		// fragments, which get assembled here
+		line = slices.Clone(line)
+		for isPrefix && err == nil {
+			var more []byte
+			more, isPrefix, err = reader.ReadLine()
+			line = append(line, more...)
+		}
+		// An unterminated last line as long as a multiple of the buffer
+		// ends with an empty fragment and io.EOF
+		if len(line) > 0 && errors.Is(err, io.EOF) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(line), nil
+}
+
+type FileReader struct {
+	file string
+	cfg  lineConfig
+}
+
+func NewFileReader(file string, opts ...LineOption) FileReader {
+	return FileReader{file: file, cfg: newLineConfig(opts)}
+}
+
+// All returns the lines of the file, or an error in place of a line.
+// The sequence can be ranged any number of times; every range reopens the
+// file and reads it from the beginning, nothing is cached between ranges
+func (r FileReader) All() iter.Seq2[string, error] {
+	return r.lines(-1)
+}
+
+// AllMaxBytes is All that stops once n bytes have been read from the file.
+// The cap is exact: a line crossing the nth byte is yielded truncated at it
+func (r FileReader) AllMaxBytes(n int64) iter.Seq2[string, error] {
+	return r.lines(max(n, 0))
+}
+
+// lines reads at most maxBytes bytes of the file, or all of it if maxBytes is negative
+func (r FileReader) lines(maxBytes int64) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		file, err := os.Open(r.file)
+		if err != nil {
+			yield("", &FileError{Path: r.file, Op: "open", Err: err})
+			return
+		}
+		defer file.Close()
+
+		var src io.Reader = file
+		if maxBytes >= 0 {
+			src = io.LimitReader(file, maxBytes)
+		}
+		// The file, not src, is what a read timeout has to close
+		lines := &lineSource{reader: newBufReader(src, r.cfg), closer: file}
+		for line, err := range bufferedSeq(lines, r.cfg) {
+			if err != nil {
+				yield("", &FileError{Path: r.file, Op: "read", Err: err})
+				return
+			}
+			if !yield(line, nil) {
+				return
+			}
+		}
+	}
+}
+
+// FileLine is a line read by DirReader along with where it came from
+type FileLine struct {
+	Path string
+	Line int // 1-based
+	Text string
+}
+
+type DirReader struct {
+	dir             string
+	glob            string
+	continueOnError bool
+}
+
+func NewDirReader(dir, glob string) DirReader {
+	return DirReader{dir: dir, glob: glob}
+}
+
+// ContinueOnError makes Lines carry on with the next file after yielding an
+// error for a file, instead of stopping the walk
+func (r DirReader) ContinueOnError() DirReader {
+	r.continueOnError = true
+	return r
+}
+
+// Lines returns the lines of every file in the directory matching the glob,
+// as one stream. Files are read one after another in sorted order, each one
+// opened only when its turn comes
+func (r DirReader) Lines() iter.Seq2[FileLine, error] {
+	return func(yield func(FileLine, error) bool) {
+		paths, err := filepath.Glob(filepath.Join(r.dir, r.glob))
+		if err != nil {
+			yield(FileLine{}, fmt.Errorf("glob: %w", err))
+			return
+		}
+		slices.Sort(paths)
+
+		for _, path := range paths {
+			n := 0
+			for text, err := range NewFileReader(path).All() {
+				if err != nil {
+					if !yield(FileLine{Path: path}, err) || !r.continueOnError {
+						return
+					}
+					break
+				}
+				n++
+				if !yield(FileLine{Path: path, Line: n, Text: text}, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+type CSVReader struct {
+	file string
+}
+
+func NewCSVReader(file string) CSVReader {
+	return CSVReader{file: file}
+}
+
+// All returns the records of the CSV file, or an error in place of a record.
+// Every record must have as many fields as the first one; a record that
+// doesn't is reported as an error and reading carries on with the next one.
+// Any other error ends the iteration
+func (r CSVReader) All() iter.Seq2[[]string, error] {
+	return func(yield func([]string, error) bool) {
+		file, err := os.Open(r.file)
+		if err != nil {
+			yield(nil, &FileError{Path: r.file, Op: "open", Err: err})
+			return
+		}
+		defer file.Close()
+
+		reader := csv.NewReader(file)
+		for {
+			record, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				if !yield(nil, &FileError{Path: r.file, Op: "read csv", Err: err}) || !errors.Is(err, csv.ErrFieldCount) {
+					return
+				}
+				continue
+			}
+			if !yield(record, nil) {
+				return
+			}
+		}
+	}
+}
+
+// WithHeader is All treating the first record as column names: every
+// following record is yielded as a map from column name to field. The maps
+// are paired with an error rather than keyed by row index, so that a short
+// row can be reported in place of its map and reading carry on
+func (r CSVReader) WithHeader() iter.Seq2[map[string]string, error] {
+	return func(yield func(map[string]string, error) bool) {
+		var header []string
+		for record, err := range r.All() {
+			if err != nil {
+				if !yield(nil, err) {
+					return
+				}
+				continue
+			}
+			if header == nil {
+				header = record
+				continue
+			}
+			row := make(map[string]string, len(header))
+			for i, name := range header {
+				row[name] = record[i]
+			}
+			if !yield(row, nil) {
+				return
+			}
+		}
+	}
+}
+
+// ShortLines tells FixedWidth what to do with a line shorter than the total
+// width of the columns
+type ShortLines int
+
+const (
+	PadShortLines    ShortLines = iota // missing bytes are filled with spaces
+	RejectShortLines                   // the line is reported as an error
+)
+
+// FixedWidthRecord is a line split by FixedWidth
+type FixedWidthRecord struct {
+	Index  int // 0-based position of the line in the file
+	Fields []string
+}
+
+// FixedWidth returns the lines of the file split into fields of the given
+// widths, in bytes, or an error in place of a record. Bytes past the last
+// column are ignored, and fields keep their padding. A line shorter than the
+// columns is handled according to short; when rejected, reading carries on
+// with the next line. Each record carries its line index next to its fields,
+// leaving the second slot to read errors and rejected lines
+func (r FileReader) FixedWidth(widths []int, short ShortLines) iter.Seq2[FixedWidthRecord, error] {
+	total := 0
+	for _, w := range widths {
+		total += max(w, 0)
+	}
+	return func(yield func(FixedWidthRecord, error) bool) {
+		i := -1
+		for line, err := range r.All() {
+			i++
+			if err != nil {
+				yield(FixedWidthRecord{}, err)
+				return
+			}
+			if len(line) < total {
+				if short == RejectShortLines {
+					err := fmt.Errorf("line %d: %d bytes, want %d", i+1, len(line), total)
+					if !yield(FixedWidthRecord{}, &FileError{Path: r.file, Op: "split", Err: err}) {
+						return
+					}
+					continue
+				}
+				line += strings.Repeat(" ", total-len(line))
+			}
+
+			fields := make([]string, len(widths))
+			rest := line
+			for j, w := range widths {
+				w = max(w, 0)
+				fields[j], rest = rest[:w], rest[w:]
+			}
+			if !yield(FixedWidthRecord{Index: i, Fields: fields}, nil) {
+				return
+			}
+		}
+	}
+}
+
+// WalkEntry is a file or directory visited by WalkDir
+type WalkEntry struct {
+	Path  string
+	Entry fs.DirEntry // nil if the path itself could not be read
+}
+
+// WalkDir returns the files and directories of the tree rooted at root, in
+// the lexical order of filepath.WalkDir, or an error in place of an entry.
+// Errors don't stop the walk; a directory that can't be read is skipped.
+// The path and its fs.DirEntry travel together in a WalkEntry, so that the
+// error filepath.WalkDir reports for an entry has a slot of its own.
+// Being a push iterator itself, filepath.WalkDir is bridged directly: stopping
+// the range ends the walk with filepath.SkipAll
+func WalkDir(root string) iter.Seq2[WalkEntry, error] {
+	return func(yield func(WalkEntry, error) bool) {
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				err = &FileError{Path: path, Op: "walk", Err: err}
+			}
+			if !yield(WalkEntry{Path: path, Entry: d}, err) {
+				return filepath.SkipAll
+			}
+			return nil
+		})
+	}
+}
+
+// CountLines returns the number of lines All would yield, counting newline
+// bytes over raw buffers instead of building a string per line
+func (r FileReader) CountLines() (int, error) {
+	file, err := os.Open(r.file)
+	if err != nil {
+		return 0, &FileError{Path: r.file, Op: "open", Err: err}
+	}
+	defer file.Close()
+
+	var (
+		buf   = make([]byte, 32*1024)
+		count int
+		last  byte = '\n'
+	)
+	for {
+		n, err := file.Read(buf)
+		if n > 0 {
+			count += bytes.Count(buf[:n], []byte{'\n'})
+			last = buf[n-1]
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return count, &FileError{Path: r.file, Op: "read", Err: err}
+		}
+	}
+	if last != '\n' {
+		count++ // last line without a trailing newline
+	}
+	return count, nil
+}
+
+// Grep returns the lines of the file matching the regular expression
+// pattern, with their line numbers, or an error in place of a line. The
+// pattern is compiled once, up front: an invalid one is reported right away
+// instead of on the first range. Matches are FileLines holding the number
+// and the text together, since the second slot carries read errors
+func Grep(r FileReader, pattern string) (iter.Seq2[FileLine, error], error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("compile pattern: %w", err)
+	}
+	return func(yield func(FileLine, error) bool) {
+		n := 0
+		for text, err := range r.All() {
+			if err != nil {
+				yield(FileLine{Path: r.file}, err)
+				return
+			}
+			n++
+			if re.MatchString(text) && !yield(FileLine{Path: r.file, Line: n, Text: text}, nil) {
+				return
+			}
+		}
+	}, nil
+}
+
+// JSONArrayReader streams the elements of a file holding a single JSON array
+type JSONArrayReader[T any] struct {
+	file string
+}
+
+func NewJSONArrayReader[T any](file string) JSONArrayReader[T] {
+	return JSONArrayReader[T]{file: file}
+}
+
+// All returns the elements of the array decoded one at a time, or an error
+// in place of an element; the array is never held in memory as a whole.
+// Decoding stops at the first error
+func (r JSONArrayReader[T]) All() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		file, err := os.Open(r.file)
+		if err != nil {
+			yield(zero, &FileError{Path: r.file, Op: "open", Err: err})
+			return
+		}
+		defer file.Close()
+
+		dec := json.NewDecoder(file)
+		if err := expectDelim(dec, '['); err != nil {
+			yield(zero, &FileError{Path: r.file, Op: "decode", Err: err})
+			return
+		}
+		for dec.More() {
+			var v T
+			if err := dec.Decode(&v); err != nil {
+				yield(zero, &FileError{Path: r.file, Op: "decode", Err: err})
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+		if err := expectDelim(dec, ']'); err != nil {
+			yield(zero, &FileError{Path: r.file, Op: "decode", Err: err})
+		}
+	}
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != want {
+		return fmt.Errorf("got %v, want %v", tok, want)
+	}
+	return nil
+}
+
+// BuildIndex maps the 1-based number of every line of the file to the byte
+// offset it starts at, for random access with ReadLineAt
+func BuildIndex(r FileReader) (map[int]int64, error) {
+	file, err := os.Open(r.file)
+	if err != nil {
+		return nil, &FileError{Path: r.file, Op: "open", Err: err}
+	}
+	defer file.Close()
+
+	index := make(map[int]int64)
+	reader := bufio.NewReader(file)
+	var offset int64
+	for n := 1; ; n++ {
+		start := offset
+		var err error
+		for {
+			// ReadSlice keeps lines longer than the buffer coming in pieces
+			var chunk []byte
+			chunk, err = reader.ReadSlice('\n')
+			offset += int64(len(chunk))
+			if !errors.Is(err, bufio.ErrBufferFull) {
+				break
+			}
+		}
+		if offset > start {
+			index[n] = start
+		}
+		if errors.Is(err, io.EOF) {
+			return index, nil
+		}
+		if err != nil {
+			return nil, &FileError{Path: r.file, Op: "read", Err: err}
+		}
+	}
+}
+
+// ReadLineAt returns the line of file starting at byte offset, as found by
+// BuildIndex
+func ReadLineAt(file string, offset int64) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", &FileError{Path: file, Op: "open", Err: err}
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return "", &FileError{Path: file, Op: "seek", Err: err}
+	}
+	for line, err := range readerSeq(f, lineConfig{}) {
+		if err != nil {
+			return "", &FileError{Path: file, Op: "read", Err: err}
+		}
+		return line, nil
+	}
+	return "", &FileError{Path: file, Op: "read", Err: io.EOF}
+}
+
+// ScanSeq returns the tokens of a caller-configured scanner, whatever its
+// split function and buffer. A scanner error is yielded after the last token
+func ScanSeq(s *bufio.Scanner) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for s.Scan() {
+			if !yield(s.Text(), nil) {
+				return
+			}
+		}
+		if err := s.Err(); err != nil {
+			yield("", fmt.Errorf("scan: %w", err))
+		}
+	}
+}
+
+// Chunks returns the content of the file in chunks of size bytes, the last
+// one possibly shorter, or an error in place of a chunk. Every chunk is a
+// fresh slice the consumer may keep
+func (r FileReader) Chunks(size int) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		if size <= 0 {
+			yield(nil, &FileError{Path: r.file, Op: "read", Err: fmt.Errorf("invalid chunk size %d", size)})
+			return
+		}
+		file, err := os.Open(r.file)
+		if err != nil {
+			yield(nil, &FileError{Path: r.file, Op: "open", Err: err})
+			return
+		}
+		defer file.Close()
+
+		buf := make([]byte, size)
+		for {
+			n, err := io.ReadFull(file, buf)
+			if n > 0 && !yield(slices.Clone(buf[:n]), nil) {
+				return
+			}
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return
+			}
+			if err != nil {
+				yield(nil, &FileError{Path: r.file, Op: "read", Err: err})
+				return
+			}
+		}
+	}
+}
+
+// AllFrom is All starting at the given 1-based line number, with every line
+// numbered so a consumer can record where it stopped and resume there later.
+// The lines before it are skipped without being turned into strings.
+// Lines are FileLines rather than number and text pairs, which leaves room
+// for a failed open or read to be reported as an error
+func (r FileReader) AllFrom(line int) iter.Seq2[FileLine, error] {
+	return func(yield func(FileLine, error) bool) {
+		file, err := os.Open(r.file)
+		if err != nil {
+			yield(FileLine{Path: r.file}, &FileError{Path: r.file, Op: "open", Err: err})
+			return
+		}
+		defer file.Close()
+
+		reader := bufio.NewReader(file)
+		n := 1
+		for ; n < line; n++ {
+			_, err := reader.ReadSlice('\n')
+			for errors.Is(err, bufio.ErrBufferFull) {
+				_, err = reader.ReadSlice('\n')
+			}
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(FileLine{Path: r.file}, &FileError{Path: r.file, Op: "read", Err: err})
+				return
+			}
+		}
+
+		for text, err := range bufferedSeq(&lineSource{reader: reader, closer: file}, r.cfg) {
+			if err != nil {
+				yield(FileLine{Path: r.file}, &FileError{Path: r.file, Op: "read", Err: err})
+				return
+			}
+			if !yield(FileLine{Path: r.file, Line: n, Text: text}, nil) {
+				return
+			}
+			n++
+		}
+	}
+}
+
+// ScanTokens returns the tokens of r as cut by split, keyed by their 0-based
+// index. Scanning stops at the first scanner error, which the returned
+// function reports afterwards (nil if the last range ended without one); a
+// token that doesn't fit the scanner's buffer shows up as bufio.ErrTooLong
+func ScanTokens(r io.Reader, split bufio.SplitFunc) (iter.Seq2[int, string], func() error) {
+	var lastErr error
+	tokens := func(yield func(int, string) bool) {
+		lastErr = nil
+		s := bufio.NewScanner(r)
+		s.Split(split)
+		i := 0
+		for token, err := range ScanSeq(s) {
+			if err != nil {
+				lastErr = err
+				return
+			}
+			if !yield(i, token) {
+				return
+			}
+			i++
+		}
+	}
+	return tokens, func() error { return lastErr }
+}
@@ -0,0 +1,805 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"iter"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestFile writes content to a file in a fresh temporary directory and
+// returns its path
+func writeTestFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "input.txt")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// collectLines ranges seq to the end, failing the test on the first error
+func collectLines(t *testing.T, seq iter.Seq2[string, error]) []string {
+	t.Helper()
+	var lines []string
+	for line, err := range seq {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestFileReaderLongLines(t *testing.T) {
+	long := strings.Repeat("x", 10*1024)
+	tests := []struct {
+		name    string
+		content string
+		opts    []LineOption
+		want    []string
+	}{
+		{"10KB line", long + "\nshort\n", nil, []string{long, "short"}},
+		{"10KB unterminated line", "short\n" + long, nil, []string{"short", long}},
+		{"exact buffer unterminated", strings.Repeat("y", 4096), nil, []string{strings.Repeat("y", 4096)}},
+		{"exact buffer multiple unterminated", strings.Repeat("z", 64), []LineOption{WithBufferSize(16)}, []string{strings.Repeat("z", 64)}},
+		{"exact buffer multiple terminated", strings.Repeat("z", 64) + "\n", []LineOption{WithBufferSize(16)}, []string{strings.Repeat("z", 64)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewFileReader(writeTestFile(t, tt.content), tt.opts...)
+			got := collectLines(t, r.All())
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("got %d lines of lengths %v, want %d", len(got), lengths(got), len(tt.want))
+			}
+
+			count, err := r.CountLines()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != len(got) {
+				t.Errorf("CountLines = %d, All yielded %d lines", count, len(got))
+			}
+		})
+	}
+}
+
+func lengths(lines []string) []int {
+	n := make([]int, len(lines))
+	for i, line := range lines {
+		n[i] = len(line)
+	}
+	return n
+}
+
+func TestFileReaderRangeTwice(t *testing.T) {
+	path := writeTestFile(t, "one\ntwo\nthree\n")
+	r := NewFileReader(path)
+
+	first, second := collectLines(t, r.All()), collectLines(t, r.All())
+	if want := []string{"one", "two", "three"}; !slices.Equal(first, want) {
+		t.Fatalf("first range = %q, want %q", first, want)
+	}
+	if !slices.Equal(first, second) {
+		t.Errorf("second range = %q, want %q", second, first)
+	}
+
+	// Nothing is cached: a range after the file changed sees the new content
+	if err := os.WriteFile(path, []byte("four\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := collectLines(t, r.All()); !slices.Equal(got, []string{"four"}) {
+		t.Errorf("range after rewrite = %q, want [four]", got)
+	}
+}
+
+func TestFileErrorOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.txt")
+	var err error
+	for _, e := range NewFileReader(path).All() {
+		err = e
+	}
+
+	var fileErr *FileError
+	if !errors.As(err, &fileErr) {
+		t.Fatalf("got %v, want a *FileError", err)
+	}
+	if fileErr.Path != path || fileErr.Op != "open" {
+		t.Errorf("got path %q and op %q, want %q and \"open\"", fileErr.Path, fileErr.Op, path)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("%v does not wrap fs.ErrNotExist", err)
+	}
+}
+
+func TestLineReader(t *testing.T) {
+	r := NewLineReader(strings.NewReader("first\nsecond\nthird\n"))
+	if got, want := collectLines(t, r.All()), []string{"first", "second", "third"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLineReaderResumes(t *testing.T) {
+	r := NewLineReader(strings.NewReader("a\nb\nc\n"))
+	for line, err := range r.All() {
+		if err != nil || line != "a" {
+			t.Fatalf("first line = %q, %v; want \"a\"", line, err)
+		}
+		break
+	}
+	if got, want := collectLines(t, r.All()), []string{"b", "c"}; !slices.Equal(got, want) {
+		t.Errorf("second range = %q, want %q", got, want)
+	}
+}
+
+func TestFileReaderAllMaxBytes(t *testing.T) {
+	var b strings.Builder
+	for i := range 50 {
+		fmt.Fprintf(&b, "line-%04d\n", i) // 10 bytes each
+	}
+	r := NewFileReader(writeTestFile(t, b.String()))
+
+	tests := []struct {
+		n         int64
+		wantLines int
+		wantLast  string
+	}{
+		{100, 10, "line-0009"},
+		{105, 11, "line-"},
+		{0, 0, ""},
+		{-1, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.n), func(t *testing.T) {
+			got := collectLines(t, r.AllMaxBytes(tt.n))
+			if len(got) != tt.wantLines {
+				t.Fatalf("got %d lines, want %d", len(got), tt.wantLines)
+			}
+			if len(got) > 0 && got[len(got)-1] != tt.wantLast {
+				t.Errorf("last line = %q, want %q", got[len(got)-1], tt.wantLast)
+			}
+			want := strings.TrimSuffix(b.String()[:max(tt.n, 0)], "\n")
+			if joined := strings.Join(got, "\n"); joined != want {
+				t.Errorf("lines don't add up to the first %d bytes of the file", tt.n)
+			}
+		})
+	}
+}
+
+func TestDirReader(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"b.txt":    "b1\nb2\n",
+		"a.txt":    "a1\n",
+		"notes.md": "skipped\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []FileLine
+	for line, err := range NewDirReader(dir, "*.txt").Lines() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, line)
+	}
+	want := []FileLine{
+		{filepath.Join(dir, "a.txt"), 1, "a1"},
+		{filepath.Join(dir, "b.txt"), 1, "b1"},
+		{filepath.Join(dir, "b.txt"), 2, "b2"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestDirReaderErrors(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"a.txt": "a1\n", "c.txt": "c1\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A directory matches the glob but can't be read as a file
+	if err := os.Mkdir(filepath.Join(dir, "b.txt"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(r DirReader) (texts []string, errs int) {
+		for line, err := range r.Lines() {
+			if err != nil {
+				errs++
+				continue
+			}
+			texts = append(texts, line.Text)
+		}
+		return texts, errs
+	}
+
+	texts, errs := read(NewDirReader(dir, "*.txt"))
+	if !slices.Equal(texts, []string{"a1"}) || errs != 1 {
+		t.Errorf("stopping on error: got %q and %d errors, want [a1] and 1", texts, errs)
+	}
+	texts, errs = read(NewDirReader(dir, "*.txt").ContinueOnError())
+	if !slices.Equal(texts, []string{"a1", "c1"}) || errs != 1 {
+		t.Errorf("continuing on error: got %q and %d errors, want [a1 c1] and 1", texts, errs)
+	}
+}
+
+func TestStdinReader(t *testing.T) {
+	r := NewStdinReader(strings.NewReader("piped\ninput\r\nlast"))
+	if got, want := collectLines(t, r.All()), []string{"piped", "input", "last"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if NewStdinReader(nil).src != stdin() {
+		t.Error("NewStdinReader(nil) doesn't share the stdin source")
+	}
+}
+
+func TestReadersAgree(t *testing.T) {
+	fixtures := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"empty", "", nil},
+		{"terminated", "a\nb\n", []string{"a", "b"}},
+		{"unterminated", "a\nb", []string{"a", "b"}},
+		{"crlf", "a\r\nb\r\n", []string{"a", "b"}},
+		{"blank lines", "\n\na\n\n", []string{"", "", "a", ""}},
+	}
+	readers := map[string]func(t *testing.T, content string) iter.Seq2[string, error]{
+		"FileReader": func(t *testing.T, content string) iter.Seq2[string, error] {
+			return NewFileReader(writeTestFile(t, content)).All()
+		},
+		"StringReader": func(_ *testing.T, content string) iter.Seq2[string, error] {
+			return NewStringReader(content).All()
+		},
+		"StdinReader": func(_ *testing.T, content string) iter.Seq2[string, error] {
+			return NewStdinReader(strings.NewReader(content)).All()
+		},
+	}
+	for _, f := range fixtures {
+		for name, lines := range readers {
+			t.Run(f.name+"/"+name, func(t *testing.T) {
+				got := collectLines(t, lines(t, f.content))
+				if !slices.Equal(got, f.want) {
+					t.Errorf("got %q, want %q", got, f.want)
+				}
+			})
+		}
+	}
+}
+
+func TestCSVReaderWithHeader(t *testing.T) {
+	path := writeTestFile(t, "name,country\nApple,United States\nSamsung\nXiaomi,China\n")
+
+	var (
+		rows []map[string]string
+		errs []error
+	)
+	for row, err := range NewCSVReader(path).WithHeader() {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	want := []map[string]string{
+		{"name": "Apple", "country": "United States"},
+		{"name": "Xiaomi", "country": "China"},
+	}
+	if !slices.EqualFunc(rows, want, maps.Equal) {
+		t.Errorf("got %v, want %v", rows, want)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], csv.ErrFieldCount) {
+		t.Errorf("got errors %v, want one csv.ErrFieldCount for the short row", errs)
+	}
+}
+
+// blockingReader returns its lines, then blocks until unblock is closed
+type blockingReader struct {
+	lines   io.Reader
+	unblock chan struct{}
+}
+
+func (r blockingReader) Read(p []byte) (int, error) {
+	if n, err := r.lines.Read(p); n > 0 || err != io.EOF {
+		return n, err
+	}
+	<-r.unblock
+	return 0, io.EOF
+}
+
+func TestLineReaderTimeout(t *testing.T) {
+	unblock := make(chan struct{})
+	defer close(unblock)
+	src := blockingReader{lines: strings.NewReader("ready\n"), unblock: unblock}
+
+	var (
+		lines []string
+		errs  []error
+	)
+	for line, err := range NewLineReader(src, WithReadTimeout(20*time.Millisecond)).All() {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if !slices.Equal(lines, []string{"ready"}) {
+		t.Errorf("got lines %q, want [ready]", lines)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], ErrReadTimeout) {
+		t.Errorf("got errors %v, want one ErrReadTimeout", errs)
+	}
+}
+
+func TestLineReaderTimeoutClosesSource(t *testing.T) {
+	before := runtime.NumGoroutine()
+	pr, pw := io.Pipe()
+	defer pw.Close()
+
+	var err error
+	for _, e := range NewLineReader(pr, WithReadTimeout(10*time.Millisecond)).All() {
+		err = e
+	}
+	if !errors.Is(err, ErrReadTimeout) {
+		t.Fatalf("got %v, want ErrReadTimeout", err)
+	}
+	// Closing the pipe unblocked the reading goroutine
+	if _, err := pw.Write([]byte("late\n")); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("write after the timeout: got %v, want io.ErrClosedPipe", err)
+	}
+	checkNoLeak(t, before)
+}
+
+func TestLineReaderTimeoutResumes(t *testing.T) {
+	r := NewLineReader(strings.NewReader("a\nb\nc\n"), WithReadTimeout(time.Second))
+	for line, err := range r.All() {
+		if err != nil || line != "a" {
+			t.Fatalf("first line = %q, %v; want \"a\"", line, err)
+		}
+		break
+	}
+	if got, want := collectLines(t, r.All()), []string{"b", "c"}; !slices.Equal(got, want) {
+		t.Errorf("second range = %q, want %q", got, want)
+	}
+}
+
+// gatedReader blocks every Read until gate is closed, and is no io.Closer a
+// timeout could use to unblock it
+type gatedReader struct {
+	r    io.Reader
+	gate chan struct{}
+}
+
+func (r gatedReader) Read(p []byte) (int, error) {
+	<-r.gate
+	return r.r.Read(p)
+}
+
+func TestLineReaderTimeoutHandsOverRead(t *testing.T) {
+	gate := make(chan struct{})
+	r := NewLineReader(gatedReader{strings.NewReader("late\nafter\n"), gate}, WithReadTimeout(10*time.Millisecond))
+
+	var err error
+	for _, e := range r.All() {
+		err = e
+	}
+	if !errors.Is(err, ErrReadTimeout) {
+		t.Fatalf("got %v, want ErrReadTimeout", err)
+	}
+
+	// The read that timed out is still running; the next range picks up its
+	// line instead of starting a read of its own on the same buffer
+	close(gate)
+	if got, want := collectLines(t, r.All()), []string{"late", "after"}; !slices.Equal(got, want) {
+		t.Errorf("second range = %q, want %q", got, want)
+	}
+}
+
+func TestFileReaderFixedWidth(t *testing.T) {
+	r := NewFileReader(writeTestFile(t, "Alice030Oslo\nBob  025Rome\nEve  04\nCarol041Lima and more\n"))
+	widths := []int{5, 3, 4}
+
+	var got []FixedWidthRecord
+	for rec, err := range r.FixedWidth(widths, PadShortLines) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, rec)
+	}
+	want := []FixedWidthRecord{
+		{0, []string{"Alice", "030", "Oslo"}},
+		{1, []string{"Bob  ", "025", "Rome"}},
+		{2, []string{"Eve  ", "04 ", "    "}},
+		{3, []string{"Carol", "041", "Lima"}},
+	}
+	equal := func(a, b FixedWidthRecord) bool { return a.Index == b.Index && slices.Equal(a.Fields, b.Fields) }
+	if !slices.EqualFunc(got, want, equal) {
+		t.Errorf("padding: got %q, want %q", got, want)
+	}
+
+	got = got[:0]
+	var errs []error
+	for rec, err := range r.FixedWidth(widths, RejectShortLines) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		got = append(got, rec)
+	}
+	if want := slices.Delete(slices.Clone(want), 2, 3); !slices.EqualFunc(got, want, equal) {
+		t.Errorf("rejecting: got %q, want %q", got, want)
+	}
+	var fileErr *FileError
+	if len(errs) != 1 || !errors.As(errs[0], &fileErr) || fileErr.Op != "split" {
+		t.Errorf("rejecting: got errors %v, want one split error for the short line", errs)
+	}
+}
+
+func TestFileReaderKeepLineEndings(t *testing.T) {
+	for _, content := range []string{
+		"one\r\ntwo\r\nthree\r\n",
+		"mixed\r\nendings\nno end",
+		"\r\n\r\n",
+	} {
+		r := NewFileReader(writeTestFile(t, content), KeepLineEndings())
+		lines := collectLines(t, r.All())
+		if got := strings.Join(lines, ""); got != content {
+			t.Errorf("round trip of %q gave %q", content, got)
+		}
+	}
+
+	r := NewFileReader(writeTestFile(t, "one\r\ntwo\r\n"))
+	if got := collectLines(t, r.All()); !slices.Equal(got, []string{"one", "two"}) {
+		t.Errorf("default: got %q, want the endings stripped", got)
+	}
+}
+
+func TestWalkDir(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"a", "a/b", "c"} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"top.txt", "a/one.txt", "a/b/two.txt"} {
+		if err := os.WriteFile(filepath.Join(root, file), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	for entry, err := range WalkDir(root) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		rel, err := filepath.Rel(root, entry.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, filepath.ToSlash(rel))
+	}
+	want := []string{".", "a", "a/b", "a/b/two.txt", "a/one.txt", "c", "top.txt"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	visited := 0
+	for range WalkDir(root) {
+		visited++
+		if visited == 2 {
+			break
+		}
+	}
+	if visited != 2 {
+		t.Errorf("early stop: visited %d entries, want 2", visited)
+	}
+}
+
+func TestWalkDirError(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	var errs []error
+	for _, err := range WalkDir(missing) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	var fileErr *FileError
+	if len(errs) != 1 || !errors.As(errs[0], &fileErr) || fileErr.Path != missing {
+		t.Errorf("got %v, want one walk error for %s", errs, missing)
+	}
+}
+
+func TestFileReaderCountLines(t *testing.T) {
+	tests := []struct {
+		content string
+		want    int
+	}{
+		{"", 0},
+		{"one", 1},
+		{"one\n", 1},
+		{"one\ntwo\n\nfour", 4},
+		{"\n\n", 2},
+		{strings.Repeat("line\n", 10000), 10000},
+	}
+	for _, tt := range tests {
+		r := NewFileReader(writeTestFile(t, tt.content))
+		got, err := r.CountLines()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want || got != Count(r) {
+			t.Errorf("CountLines of %.20q = %d, want %d like All", tt.content, got, tt.want)
+		}
+	}
+
+	_, err := NewFileReader(filepath.Join(t.TempDir(), "missing.txt")).CountLines()
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing file: got %v, want fs.ErrNotExist", err)
+	}
+}
+
+// BenchmarkCountLines compares CountLines with counting what All yields,
+// which builds a string per line
+func BenchmarkCountLines(b *testing.B) {
+	path := filepath.Join(b.TempDir(), "input.txt")
+	content := strings.Repeat("Lorem ipsum dolor sit amet, consectetur adipiscing elit\n", 20000)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		b.Fatal(err)
+	}
+	r := NewFileReader(path)
+
+	b.Run("CountLines", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			if _, err := r.CountLines(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("All", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			Count(r)
+		}
+	})
+}
+
+func TestGrep(t *testing.T) {
+	path := writeTestFile(t, "error: disk\ninfo: boot\nerror: net\nwarning: error-prone\n")
+	seq, err := Grep(NewFileReader(path), `^error:`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []FileLine
+	for line, err := range seq {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, line)
+	}
+	want := []FileLine{{path, 1, "error: disk"}, {path, 3, "error: net"}}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if _, err := Grep(NewFileReader(path), `(unclosed`); err == nil {
+		t.Error("an invalid pattern was accepted")
+	}
+}
+
+func TestJSONArrayReader(t *testing.T) {
+	type device struct {
+		Name    string `json:"name"`
+		Country string `json:"country"`
+	}
+	path := writeTestFile(t, `[
+		{"name": "Apple", "country": "United States"},
+		{"name": "Samsung", "country": "South Korea"},
+		{"name": "Xiaomi", "country": "China"}
+	]`)
+
+	var got []device
+	for d, err := range NewJSONArrayReader[device](path).All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, d)
+	}
+	want := []device{{"Apple", "United States"}, {"Samsung", "South Korea"}, {"Xiaomi", "China"}}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestJSONArrayReaderErrors(t *testing.T) {
+	for _, content := range []string{`{"not": "an array"}`, `[1, 2`, `[1, "two"]`} {
+		var (
+			values []int
+			err    error
+		)
+		for v, e := range NewJSONArrayReader[int](writeTestFile(t, content)).All() {
+			if e != nil {
+				err = e
+				continue
+			}
+			values = append(values, v)
+		}
+		var fileErr *FileError
+		if !errors.As(err, &fileErr) || fileErr.Op != "decode" {
+			t.Errorf("%s: got values %v and error %v, want a decode error", content, values, err)
+		}
+	}
+}
+
+func TestBuildIndex(t *testing.T) {
+	long := strings.Repeat("x", 5000) // longer than the read buffer
+	lines := []string{"first", "", long, "fourth\r", "last"}
+	path := writeTestFile(t, strings.Join(lines, "\n"))
+
+	index, err := BuildIndex(NewFileReader(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index) != len(lines) {
+		t.Fatalf("indexed %d lines, want %d", len(index), len(lines))
+	}
+	for _, n := range []int{4, 1, 3, 5, 2} {
+		line, err := ReadLineAt(path, index[n])
+		if err != nil {
+			t.Fatalf("line %d: %v", n, err)
+		}
+		// ReadLineAt strips "\r\n" like All
+		if want := strings.TrimSuffix(lines[n-1], "\r"); line != want {
+			t.Errorf("line %d at offset %d = %.20q, want %.20q", n, index[n], line, want)
+		}
+	}
+
+	if _, err := ReadLineAt(path, 1<<20); !errors.Is(err, io.EOF) {
+		t.Errorf("offset past the end: got %v, want io.EOF", err)
+	}
+}
+
+func TestScanSeq(t *testing.T) {
+	s := bufio.NewScanner(strings.NewReader("  the quick\tbrown\n\nfox  "))
+	s.Split(bufio.ScanWords)
+	if got, want := collectLines(t, ScanSeq(s)), []string{"the", "quick", "brown", "fox"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestScanSeqError(t *testing.T) {
+	s := bufio.NewScanner(strings.NewReader("short " + strings.Repeat("x", 100)))
+	s.Buffer(make([]byte, 16), 16)
+	s.Split(bufio.ScanWords)
+
+	var (
+		tokens []string
+		err    error
+	)
+	for token, e := range ScanSeq(s) {
+		if e != nil {
+			err = e
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+	if !slices.Equal(tokens, []string{"short"}) || !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("got %q and %v, want [short] and bufio.ErrTooLong", tokens, err)
+	}
+}
+
+func TestLineReaderBufferSize(t *testing.T) {
+	lines := []string{"short", strings.Repeat("a", 40), strings.Repeat("b", 16), strings.Repeat("c", 17), ""}
+	content := strings.Join(lines, "\n") + "\n"
+
+	got := collectLines(t, NewLineReader(strings.NewReader(content), WithBufferSize(16)).All())
+	if !slices.Equal(got, lines) {
+		t.Errorf("got lines of lengths %v, want %v", lengths(got), lengths(lines))
+	}
+
+	crlf := strings.Repeat("d", 15) + "\r\n" + strings.Repeat("e", 30) + "\r\n"
+	got = collectLines(t, NewLineReader(strings.NewReader(crlf), WithBufferSize(16), KeepLineEndings()).All())
+	if joined := strings.Join(got, ""); len(got) != 2 || joined != crlf {
+		t.Errorf("got %q, want the two lines of %q", got, crlf)
+	}
+}
+
+func TestFileReaderChunks(t *testing.T) {
+	content := strings.Repeat("0123456789", 10) + "abc"
+	r := NewFileReader(writeTestFile(t, content))
+
+	var (
+		sizes  []int
+		chunks [][]byte
+	)
+	for chunk, err := range r.Chunks(16) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes = append(sizes, len(chunk))
+		chunks = append(chunks, chunk)
+	}
+	if want := []int{16, 16, 16, 16, 16, 16, 7}; !slices.Equal(sizes, want) {
+		t.Errorf("chunk sizes = %v, want %v", sizes, want)
+	}
+	if got := bytes.Join(chunks, nil); string(got) != content {
+		t.Errorf("chunks joined to %q, want %q", got, content)
+	}
+
+	for _, err := range r.Chunks(0) {
+		var fileErr *FileError
+		if !errors.As(err, &fileErr) || fileErr.Op != "read" {
+			t.Errorf("Chunks(0) yielded %v, want a read FileError", err)
+		}
+	}
+}
+
+func TestFileReaderAllFrom(t *testing.T) {
+	path := writeTestFile(t, "one\n"+strings.Repeat("2", 5000)+"\nthree\nfour\nfive\n")
+	var got []FileLine
+	for line, err := range NewFileReader(path).AllFrom(3) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, line)
+	}
+	want := []FileLine{{path, 3, "three"}, {path, 4, "four"}, {path, 5, "five"}}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for line := range NewFileReader(path).AllFrom(6) {
+		t.Errorf("AllFrom past the end yielded %v", line)
+	}
+}
+
+func TestScanTokens(t *testing.T) {
+	tokens, tokenErr := ScanTokens(strings.NewReader("añ€😀"), bufio.ScanRunes)
+	var got []string
+	for i, token := range tokens {
+		if i != len(got) {
+			t.Errorf("token %d yielded with index %d", len(got), i)
+		}
+		got = append(got, token)
+	}
+	if want := []string{"a", "ñ", "€", "😀"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if err := tokenErr(); err != nil {
+		t.Errorf("error = %v, want nil", err)
+	}
+}
+
+func TestScanTokensTooLong(t *testing.T) {
+	tokens, tokenErr := ScanTokens(strings.NewReader("short\n"+strings.Repeat("x", bufio.MaxScanTokenSize+1)+"\n"), bufio.ScanLines)
+	var got []string
+	for _, token := range tokens {
+		got = append(got, token)
+	}
+	if !slices.Equal(got, []string{"short"}) {
+		t.Errorf("got %d tokens of lengths %v, want [short]", len(got), lengths(got))
+	}
+	if err := tokenErr(); !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("error = %v, want bufio.ErrTooLong", err)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"iter"
+)
+
+// LineScanner steps through a line sequence on demand, the way Exercise 6
+// does with iter.Pull2
+type LineScanner struct {
+	next func() (string, error, bool)
+	stop func()
+}
+
+func NewLineScanner(seq iter.Seq2[string, error]) *LineScanner {
+	next, stop := iter.Pull2(seq)
+	return &LineScanner{next: next, stop: stop}
+}
+
+// Next returns the next line or error; ok is false once the lines are
+// exhausted or the scanner is stopped
+func (s *LineScanner) Next() (line string, err error, ok bool) {
+	return s.next()
+}
+
+// Stop releases the underlying sequence. It is safe to call more than once
+func (s *LineScanner) Stop() {
+	s.stop()
+}
+
+// Until yields the remaining lines up to and including the first one
+// matching pred, then stops the scanner. Errors are yielded in place of a
+// line and do not end the iteration by themselves
+func (s *LineScanner) Until(pred func(string) bool) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for line, err, ok := s.Next(); ok; line, err, ok = s.Next() {
+			if !yield(line, err) {
+				return
+			}
+			if err == nil && pred(line) {
+				s.Stop()
+				return
+			}
+		}
+	}
+}
+
+var (
+	// ErrPaused is returned by IterController.Next while the controller is paused
+	ErrPaused = errors.New("iterator paused")
+	// ErrExhausted is returned by IterController.Next once the sequence is
+	// exhausted or the controller is stopped
+	ErrExhausted = errors.New("iterator exhausted")
+)
+
+// IterController steps through a sequence on demand, like iter.Pull2, and can
+// be paused in between, e.g. to step through lines interactively
+type IterController[K, V any] struct {
+	next   func() (K, V, bool)
+	stop   func()
+	paused bool
+}
+
+func NewIterController[K, V any](seq iter.Seq2[K, V]) *IterController[K, V] {
+	next, stop := iter.Pull2(seq)
+	return &IterController[K, V]{next: next, stop: stop}
+}
+
+// Next returns the next pair. It returns ErrPaused without advancing while
+// the controller is paused, and ErrExhausted once there is nothing left
+func (c *IterController[K, V]) Next() (K, V, error) {
+	if c.paused {
+		var (
+			zeroK K
+			zeroV V
+		)
+		return zeroK, zeroV, ErrPaused
+	}
+	k, v, ok := c.next()
+	if !ok {
+		return k, v, ErrExhausted
+	}
+	return k, v, nil
+}
+
+// Pause makes Next return ErrPaused until Resume is called
+func (c *IterController[K, V]) Pause() {
+	c.paused = true
+}
+
+// Resume lets Next advance again after Pause
+func (c *IterController[K, V]) Resume() {
+	c.paused = false
+}
+
+// Stop releases the underlying sequence. It is safe to call more than once
+func (c *IterController[K, V]) Stop() {
+	c.stop()
+}
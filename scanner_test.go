@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLineScannerStop(t *testing.T) {
+	r := NewFileReader(writeTestFile(t, "one\ntwo STOP\nthree\n"))
+	s := NewLineScanner(r.All())
+
+	var read []string
+	for line, err, ok := s.Next(); ok; line, err, ok = s.Next() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		read = append(read, line)
+		if strings.Contains(line, "STOP") {
+			s.Stop()
+		}
+	}
+	if want := []string{"one", "two STOP"}; !slices.Equal(read, want) {
+		t.Errorf("read %q, want %q", read, want)
+	}
+
+	s.Stop() // stopping again is a no-op
+	if line, err, ok := s.Next(); ok || err != nil || line != "" {
+		t.Errorf("Next after Stop = %q, %v, %v; want nothing", line, err, ok)
+	}
+}
+
+func TestLineScannerUntil(t *testing.T) {
+	r := NewFileReader(writeTestFile(t, "one\ntwo STOP\nthree\n"))
+	s := NewLineScanner(r.All())
+	defer s.Stop()
+
+	got := collectLines(t, s.Until(func(line string) bool { return strings.Contains(line, "STOP") }))
+	if want := []string{"one", "two STOP"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if _, _, ok := s.Next(); ok {
+		t.Error("Until left the scanner running")
+	}
+}
+
+func TestIterController(t *testing.T) {
+	c := NewIterController(slices.All([]string{"a", "b", "c"}))
+	defer c.Stop()
+
+	if i, v, err := c.Next(); err != nil || i != 0 || v != "a" {
+		t.Fatalf("Next = %d, %q, %v; want 0, \"a\", nil", i, v, err)
+	}
+	c.Pause()
+	for range 2 {
+		if i, v, err := c.Next(); !errors.Is(err, ErrPaused) || i != 0 || v != "" {
+			t.Fatalf("paused Next = %d, %q, %v; want ErrPaused", i, v, err)
+		}
+	}
+	c.Resume()
+	// Pausing didn't advance the sequence
+	if i, v, err := c.Next(); err != nil || i != 1 || v != "b" {
+		t.Fatalf("resumed Next = %d, %q, %v; want 1, \"b\", nil", i, v, err)
+	}
+	if _, v, err := c.Next(); err != nil || v != "c" {
+		t.Fatalf("Next = %q, %v; want \"c\", nil", v, err)
+	}
+	if _, _, err := c.Next(); !errors.Is(err, ErrExhausted) {
+		t.Errorf("Next past the end: got %v, want ErrExhausted", err)
+	}
+}
+
+func TestIterControllerStop(t *testing.T) {
+	c := NewIterController(slices.All([]int{1, 2, 3}))
+	c.Next()
+	c.Stop()
+	c.Stop() // stopping again is a no-op
+	if _, _, err := c.Next(); !errors.Is(err, ErrExhausted) {
+		t.Errorf("Next after Stop: got %v, want ErrExhausted", err)
+	}
+}
@@ -0,0 +1,1094 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"iter"
+	"math/bits"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Pair is a key and value taken out of an iter.Seq2
+type Pair[K, V any] struct {
+	Key   K
+	Value V
+}
+
+// Drain consumes seq to the end discarding every value, so the source
+// gets to run its cleanup (e.g. close a file)
+func Drain[V any](seq iter.Seq[V]) {
+	for range seq {
+	}
+}
+
+// Drain2 is Drain for iter.Seq2
+func Drain2[K, V any](seq iter.Seq2[K, V]) {
+	for range seq {
+	}
+}
+
+// Last returns the final value of seq, or false if seq is empty
+func Last[V any](seq iter.Seq[V]) (V, bool) {
+	var (
+		last V
+		ok   bool
+	)
+	for v := range seq {
+		last, ok = v, true
+	}
+	return last, ok
+}
+
+// Last2 returns the final pair of seq, or false if seq is empty
+func Last2[K, V any](seq iter.Seq2[K, V]) (K, V, bool) {
+	var (
+		lastK K
+		lastV V
+		ok    bool
+	)
+	for k, v := range seq {
+		lastK, lastV, ok = k, v, true
+	}
+	return lastK, lastV, ok
+}
+
+// Partition splits seq into the pairs that satisfy pred and the rest.
+//
+// The source is pulled once and shared: whichever side is being ranged pulls
+// from it and buffers the pairs that belong to the other side until that side
+// is ranged. In the worst case (consuming one side fully before the other) the
+// whole other side ends up buffered in memory. Each side is single-use: once
+// its range ends, pairs for it are no longer buffered, and once both sides
+// are done the source is stopped. If only one side is ever consumed, the
+// source is left suspended until that side ends and the other one is ranged
+func Partition[K, V any](seq iter.Seq2[K, V], pred func(K, V) bool) (matched, unmatched iter.Seq2[K, V]) {
+	var (
+		next func() (K, V, bool)
+		stop func()
+	)
+	sides := [2]partitionSide[K, V]{}
+	pull := func() (K, V, bool) {
+		if next == nil {
+			next, stop = iter.Pull2(seq)
+		}
+		return next()
+	}
+
+	side := func(own int) iter.Seq2[K, V] {
+		return func(yield func(K, V) bool) {
+			s, other := &sides[own], &sides[1-own]
+			if s.done {
+				return
+			}
+			defer func() {
+				s.done, s.buf = true, nil
+				if other.done && stop != nil {
+					stop()
+				}
+			}()
+
+			for len(s.buf) > 0 {
+				p := s.buf[0]
+				s.buf = s.buf[1:]
+				if !yield(p.Key, p.Value) {
+					return
+				}
+			}
+			for {
+				k, v, ok := pull()
+				if !ok {
+					return
+				}
+				if pred(k, v) == (own == 0) {
+					if !yield(k, v) {
+						return
+					}
+					continue
+				}
+				if !other.done {
+					other.buf = append(other.buf, Pair[K, V]{k, v})
+				}
+			}
+		}
+	}
+	return side(0), side(1)
+}
+
+type partitionSide[K, V any] struct {
+	buf  []Pair[K, V]
+	done bool
+}
+
+// Nth returns the value at 0-based index n and stops seq right there.
+// It returns false if seq is shorter than n+1
+func Nth[V any](seq iter.Seq[V], n int) (V, bool) {
+	if n >= 0 {
+		i := 0
+		for v := range seq {
+			if i == n {
+				return v, true
+			}
+			i++
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// First returns the first pair of seq satisfying pred and stops seq right
+// there. It returns zero values and false if nothing matches
+func First[K, V any](seq iter.Seq2[K, V], pred func(K, V) bool) (K, V, bool) {
+	for k, v := range seq {
+		if pred(k, v) {
+			return k, v, true
+		}
+	}
+	var (
+		zeroK K
+		zeroV V
+	)
+	return zeroK, zeroV, false
+}
+
+// Any reports whether some pair of seq satisfies pred
+func Any[K, V any](seq iter.Seq2[K, V], pred func(K, V) bool) bool {
+	_, _, ok := First(seq, pred)
+	return ok
+}
+
+// All reports whether every pair of seq satisfies pred.
+// It stops seq at the first pair that does not
+func All[K, V any](seq iter.Seq2[K, V], pred func(K, V) bool) bool {
+	_, _, ok := First(seq, func(k K, v V) bool { return !pred(k, v) })
+	return !ok
+}
+
+// Cache records the pairs of seq as they are pulled and replays them on
+// later ranges, so the source is pulled at most once per element. A range
+// that goes past what was recorded resumes pulling from the source where the
+// last one stopped; the source is stopped once it is exhausted.
+//
+// The returned sequence is meant for a single consumer: ranging it from
+// several goroutines at once is not safe
+func Cache[K, V any](seq iter.Seq2[K, V]) iter.Seq2[K, V] {
+	var (
+		cache []Pair[K, V]
+		next  func() (K, V, bool)
+		stop  func()
+		done  bool
+	)
+	return func(yield func(K, V) bool) {
+		for i := 0; ; i++ {
+			if i == len(cache) {
+				if done {
+					return
+				}
+				if next == nil {
+					next, stop = iter.Pull2(seq)
+				}
+				k, v, ok := next()
+				if !ok {
+					done = true
+					stop()
+					return
+				}
+				cache = append(cache, Pair[K, V]{k, v})
+			}
+			if !yield(cache[i].Key, cache[i].Value) {
+				return
+			}
+		}
+	}
+}
+
+// Scan yields the running accumulation of seq: f(init, v0), then
+// f(f(init, v0), v1) and so on
+func Scan[V, Acc any](seq iter.Seq[V], init Acc, f func(Acc, V) Acc) iter.Seq[Acc] {
+	return func(yield func(Acc) bool) {
+		acc := init
+		for v := range seq {
+			acc = f(acc, v)
+			if !yield(acc) {
+				return
+			}
+		}
+	}
+}
+
+// TakeWhile yields the values of seq as long as pred holds and stops seq at
+// the first value that fails it
+func TakeWhile[V any](seq iter.Seq[V], pred func(V) bool) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for v := range seq {
+			if !pred(v) || !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// DropWhile skips the leading values of seq satisfying pred and yields the
+// rest, starting with the first value that fails it
+func DropWhile[V any](seq iter.Seq[V], pred func(V) bool) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		dropping := true
+		for v := range seq {
+			if dropping && pred(v) {
+				continue
+			}
+			dropping = false
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Compact yields the values of seq that are not the zero value of V,
+// e.g. drops blank lines or zeros
+func Compact[V comparable](seq iter.Seq[V]) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		var zero V
+		for v := range seq {
+			if v == zero {
+				continue
+			}
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// UntilError yields the values of seq until the first error, then stops seq.
+// The returned function reports that error, or nil if the last range ended
+// without one (the source finished or the consumer stopped early)
+func UntilError[K any](seq iter.Seq2[K, error]) (iter.Seq[K], func() error) {
+	var lastErr error
+	values := func(yield func(K) bool) {
+		lastErr = nil
+		for k, err := range seq {
+			if err != nil {
+				lastErr = err
+				return
+			}
+			if !yield(k) {
+				return
+			}
+		}
+	}
+	return values, func() error { return lastErr }
+}
+
+// Swap yields the pairs of seq with key and value swapped
+func Swap[K, V any](seq iter.Seq2[K, V]) iter.Seq2[V, K] {
+	return func(yield func(V, K) bool) {
+		for k, v := range seq {
+			if !yield(v, k) {
+				return
+			}
+		}
+	}
+}
+
+// Unzip2 drains seq in a single pass and returns its keys and values as
+// parallel slices
+func Unzip2[K, V any](seq iter.Seq2[K, V]) (keys []K, values []V) {
+	for k, v := range seq {
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+	return keys, values
+}
+
+// ZipSeq pairs the values of a and b by position and stops at the end of the
+// shorter one. Both sources are pulled with iter.Pull and always stopped
+func ZipSeq[A, B any](a iter.Seq[A], b iter.Seq[B]) iter.Seq2[A, B] {
+	return func(yield func(A, B) bool) {
+		nextA, stopA := iter.Pull(a)
+		defer stopA()
+		nextB, stopB := iter.Pull(b)
+		defer stopB()
+
+		for {
+			va, ok := nextA()
+			if !ok {
+				return
+			}
+			vb, ok := nextB()
+			if !ok {
+				return
+			}
+			if !yield(va, vb) {
+				return
+			}
+		}
+	}
+}
+
+// Cycle yields the values of seq over and over, forever; the consumer has to
+// break to stop it. seq must be finite: it is ranged once, recording its
+// values, and every repetition after that is served from the recording.
+// An empty seq yields nothing
+func Cycle[V any](seq iter.Seq[V]) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		var buf []V
+		for v := range seq {
+			buf = append(buf, v)
+			if !yield(v) {
+				return
+			}
+		}
+		if len(buf) == 0 {
+			return
+		}
+		for {
+			for _, v := range buf {
+				if !yield(v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// FromSlice yields the values of s, without their indices
+func FromSlice[V any](s []V) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, v := range s {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// FromMapValues yields the values of m, in unspecified order
+func FromMapValues[K comparable, V any](m map[K]V) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, v := range m {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// PartitionBy splits seq into the pairs that satisfy pred and the rest.
+// Unlike Partition, both sides are views over a Cache of seq: the source is
+// still pulled only once, but every pair it yields stays buffered for the
+// lifetime of the returned sequences, which in exchange can be ranged any
+// number of times and in any interleaving
+func PartitionBy[K, V any](seq iter.Seq2[K, V], pred func(K, V) bool) (matched, rest iter.Seq2[K, V]) {
+	cached := Cache(seq)
+	matched = filter2(cached, pred)
+	rest = filter2(cached, func(k K, v V) bool { return !pred(k, v) })
+	return matched, rest
+}
+
+func filter2[K, V any](seq iter.Seq2[K, V], pred func(K, V) bool) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for k, v := range seq {
+			if pred(k, v) && !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// Reindex replaces the keys of seq with a fresh 0-based counter over the
+// pairs it yields, closing the gaps left by filtering an indexed source
+func Reindex[V any](seq iter.Seq2[int, V]) iter.Seq2[int, V] {
+	return func(yield func(int, V) bool) {
+		i := 0
+		for _, v := range seq {
+			if !yield(i, v) {
+				return
+			}
+			i++
+		}
+	}
+}
+
+// Tap calls fn for every value of seq right before handing it on unchanged.
+// Values never reached because the consumer stopped are not tapped
+func Tap[V any](seq iter.Seq[V], fn func(V)) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for v := range seq {
+			fn(v)
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Tap2 is Tap for iter.Seq2
+func Tap2[K, V any](seq iter.Seq2[K, V], fn func(K, V)) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for k, v := range seq {
+			fn(k, v)
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// Equal reports whether a and b yield the same values in the same order.
+// Both are pulled in lockstep and stopped at the first difference
+func Equal[V comparable](a, b iter.Seq[V]) bool {
+	nextA, stopA := iter.Pull(a)
+	defer stopA()
+	nextB, stopB := iter.Pull(b)
+	defer stopB()
+
+	for {
+		va, okA := nextA()
+		vb, okB := nextB()
+		if okA != okB || va != vb {
+			return false
+		}
+		if !okA {
+			return true
+		}
+	}
+}
+
+// Equal2 is Equal for iter.Seq2, comparing both keys and values
+func Equal2[K, V comparable](a, b iter.Seq2[K, V]) bool {
+	nextA, stopA := iter.Pull2(a)
+	defer stopA()
+	nextB, stopB := iter.Pull2(b)
+	defer stopB()
+
+	for {
+		ka, va, okA := nextA()
+		kb, vb, okB := nextB()
+		if okA != okB || ka != kb || va != vb {
+			return false
+		}
+		if !okA {
+			return true
+		}
+	}
+}
+
+// Histogram drains seq and counts its values into buckets equal-width
+// buckets spanning [min, max). Values below min are counted in the first
+// bucket and values at or above max in the last one, so the counts always add
+// up to the length of seq. It returns nil if buckets is not positive or the
+// range is empty
+func Histogram[K any](seq iter.Seq2[K, int], buckets int, min, max int) []int {
+	if buckets <= 0 || max <= min {
+		return nil
+	}
+	counts := make([]int, buckets)
+	// Offsets and the width are taken as uint64, where they always fit, and
+	// the bucket index is worked out in 128 bits so that nothing overflows
+	// even when the range spans all of int
+	width := uint64(max - min)
+	for _, v := range seq {
+		switch {
+		case v < min:
+			counts[0]++
+		case v >= max:
+			counts[buckets-1]++
+		default:
+			hi, lo := bits.Mul64(uint64(v-min), uint64(buckets))
+			i, _ := bits.Div64(hi, lo, width)
+			counts[i]++
+		}
+	}
+	return counts
+}
+
+// MapErr applies f to every value of seq and yields its result and error,
+// carrying on past failures. On error the result is the zero value of R
+func MapErr[V, R any](seq iter.Seq[V], f func(V) (R, error)) iter.Seq2[R, error] {
+	return func(yield func(R, error) bool) {
+		for v := range seq {
+			r, err := f(v)
+			if err != nil {
+				var zero R
+				r = zero
+			}
+			if !yield(r, err) {
+				return
+			}
+		}
+	}
+}
+
+// Product yields every combination of a pair of a with a pair of b, going
+// through b for each pair of a in turn. a is ranged once, while b is ranged
+// once up front and buffered in full so it can be replayed for every pair of
+// a; b must therefore be finite. Stopping early stops a as well
+func Product[K1, V1, K2, V2 any](a iter.Seq2[K1, V1], b iter.Seq2[K2, V2]) iter.Seq[Pair[Pair[K1, V1], Pair[K2, V2]]] {
+	return func(yield func(Pair[Pair[K1, V1], Pair[K2, V2]]) bool) {
+		var bs []Pair[K2, V2]
+		for k, v := range b {
+			bs = append(bs, Pair[K2, V2]{k, v})
+		}
+		if len(bs) == 0 {
+			return
+		}
+		for k, v := range a {
+			pa := Pair[K1, V1]{k, v}
+			for _, pb := range bs {
+				if !yield(Pair[Pair[K1, V1], Pair[K2, V2]]{pa, pb}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Tally drains seq and counts how many times each distinct value occurs.
+// It is the counting counterpart of Histogram, which buckets ints by range
+func Tally[V comparable](seq iter.Seq[V]) map[V]int {
+	counts := make(map[V]int)
+	for v := range seq {
+		counts[v]++
+	}
+	return counts
+}
+
+// Frequencies drains seq and counts how many times each key occurs
+func Frequencies[K comparable, V any](seq iter.Seq2[K, V]) map[K]int {
+	counts := make(map[K]int)
+	for k := range seq {
+		counts[k]++
+	}
+	return counts
+}
+
+// ValueFrequencies drains seq and counts how many times each value occurs
+func ValueFrequencies[K any, V comparable](seq iter.Seq2[K, V]) map[V]int {
+	return Frequencies(Swap(seq))
+}
+
+// ChunkByKey groups runs of consecutive pairs sharing a key into a single
+// (key, values) pair. Only adjacent pairs are merged: a key that shows up
+// again after a different one starts a new group
+func ChunkByKey[K comparable, V any](seq iter.Seq2[K, V]) iter.Seq2[K, []V] {
+	return func(yield func(K, []V) bool) {
+		var (
+			key    K
+			values []V
+		)
+		for k, v := range seq {
+			if values != nil && k != key {
+				if !yield(key, values) {
+					return
+				}
+				values = nil
+			}
+			key = k
+			values = append(values, v)
+		}
+		if values != nil {
+			yield(key, values)
+		}
+	}
+}
+
+// Drain2WithStop pulls seq pair by pair and hands each one to handle, until
+// handle returns false or seq ends. It replaces the manual next/stop loop of
+// iter.Pull2 and always calls stop, so the source gets to clean up
+func Drain2WithStop[K, V any](seq iter.Seq2[K, V], handle func(K, V) bool) {
+	next, stop := iter.Pull2(seq)
+	defer stop()
+	for k, v, ok := next(); ok && handle(k, v); k, v, ok = next() {
+	}
+}
+
+// TakeDistinct yields the pairs of seq whose key hasn't been seen yet, and
+// stops seq as soon as n distinct keys have been yielded
+func TakeDistinct[K comparable, V any](seq iter.Seq2[K, V], n int) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		if n <= 0 {
+			return
+		}
+		seen := make(map[K]struct{}, n)
+		for k, v := range seq {
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+			if !yield(k, v) || len(seen) == n {
+				return
+			}
+		}
+	}
+}
+
+// Interleave yields one value from each of seqs in turn, skipping the ones
+// that are exhausted, until all of them are. Every source is pulled with
+// iter.Pull and stopped when the range ends, early or not
+func Interleave[V any](seqs ...iter.Seq[V]) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		nexts := make([]func() (V, bool), len(seqs))
+		for i, seq := range seqs {
+			next, stop := iter.Pull(seq)
+			defer stop()
+			nexts[i] = next
+		}
+
+		for len(nexts) > 0 {
+			active := nexts[:0]
+			for _, next := range nexts {
+				v, ok := next()
+				if !ok {
+					continue
+				}
+				if !yield(v) {
+					return
+				}
+				active = append(active, next)
+			}
+			nexts = active
+		}
+	}
+}
+
+// Interleave2 is Interleave for iter.Seq2
+func Interleave2[K, V any](seqs ...iter.Seq2[K, V]) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		nexts := make([]func() (K, V, bool), len(seqs))
+		for i, seq := range seqs {
+			next, stop := iter.Pull2(seq)
+			defer stop()
+			nexts[i] = next
+		}
+
+		for len(nexts) > 0 {
+			active := nexts[:0]
+			for _, next := range nexts {
+				k, v, ok := next()
+				if !ok {
+					continue
+				}
+				if !yield(k, v) {
+					return
+				}
+				active = append(active, next)
+			}
+			nexts = active
+		}
+	}
+}
+
+// DistinctWindow drops a value of seq if it is among the last window distinct
+// values yielded. Memory is bounded by window: a value that has fallen out of
+// the window is yielded again
+func DistinctWindow[V comparable](seq iter.Seq[V], window int) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		if window <= 0 {
+			for v := range seq {
+				if !yield(v) {
+					return
+				}
+			}
+			return
+		}
+
+		ring := make([]V, 0, window)
+		oldest := 0
+		seen := make(map[V]struct{}, window)
+		for v := range seq {
+			if _, ok := seen[v]; ok {
+				continue
+			}
+			if len(ring) < window {
+				ring = append(ring, v)
+			} else {
+				delete(seen, ring[oldest])
+				ring[oldest] = v
+				oldest = (oldest + 1) % window
+			}
+			seen[v] = struct{}{}
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Memoize is Cache for iter.Seq: the source is pulled at most once per value,
+// later ranges replay what was recorded and resume pulling past its end
+func Memoize[V any](seq iter.Seq[V]) iter.Seq[V] {
+	cached := Cache(func(yield func(V, struct{}) bool) {
+		for v := range seq {
+			if !yield(v, struct{}{}) {
+				return
+			}
+		}
+	})
+	return func(yield func(V) bool) {
+		for v := range cached {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// OffsetIndex adds start to every key of seq, e.g. 1 to number from one
+func OffsetIndex[V any](seq iter.Seq2[int, V], start int) iter.Seq2[int, V] {
+	return func(yield func(int, V) bool) {
+		for i, v := range seq {
+			if !yield(i+start, v) {
+				return
+			}
+		}
+	}
+}
+
+// SortedByKey buffers all of seq and yields its pairs ordered by key. Pairs
+// with equal keys keep their original order
+func SortedByKey[K cmp.Ordered, V any](seq iter.Seq2[K, V]) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		var pairs []Pair[K, V]
+		for k, v := range seq {
+			pairs = append(pairs, Pair[K, V]{k, v})
+		}
+		slices.SortStableFunc(pairs, func(a, b Pair[K, V]) int { return cmp.Compare(a.Key, b.Key) })
+		for _, p := range pairs {
+			if !yield(p.Key, p.Value) {
+				return
+			}
+		}
+	}
+}
+
+// CollectErrors drains seq, keeping the values paired with a nil error apart
+// from the non-nil errors, instead of stopping at the first error
+func CollectErrors[K any](seq iter.Seq2[K, error]) ([]K, []error) {
+	var (
+		values []K
+		errs   []error
+	)
+	for k, err := range seq {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		values = append(values, k)
+	}
+	return values, errs
+}
+
+// Flatten2 yields every value of every slice in seq, each paired with the
+// key the slice came with. It undoes grouping such as ChunkByKey
+func Flatten2[K, V any](seq iter.Seq2[K, []V]) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for k, vs := range seq {
+			for _, v := range vs {
+				if !yield(k, v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// DrainN consumes at most max pairs of seq and reports how many it consumed
+// and whether it hit the cap, in which case seq is stopped and may well be
+// infinite
+func DrainN[K, V any](seq iter.Seq2[K, V], max int) (int, bool) {
+	if max <= 0 {
+		return 0, true
+	}
+	n := 0
+	for range seq {
+		n++
+		if n == max {
+			return n, true
+		}
+	}
+	return n, false
+}
+
+// SplitAt cuts seq into segments, starting a new one at every pair
+// satisfying pred, which becomes the first pair of that segment. Pairs before
+// the first match make up a segment of their own. Segments are yielded with
+// their 0-based index
+func SplitAt[K, V any](seq iter.Seq2[K, V], pred func(K, V) bool) iter.Seq2[int, []Pair[K, V]] {
+	return func(yield func(int, []Pair[K, V]) bool) {
+		var segment []Pair[K, V]
+		i := 0
+		for k, v := range seq {
+			if pred(k, v) && len(segment) > 0 {
+				if !yield(i, segment) {
+					return
+				}
+				segment = nil
+				i++
+			}
+			segment = append(segment, Pair[K, V]{k, v})
+		}
+		if len(segment) > 0 {
+			yield(i, segment)
+		}
+	}
+}
+
+// Shuffle buffers all of seq before yielding anything, then yields its pairs
+// in an order drawn from r, so a seeded r gives a reproducible order.
+// A nil r uses the global source
+func Shuffle[K, V any](seq iter.Seq2[K, V], r *rand.Rand) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		var pairs []Pair[K, V]
+		for k, v := range seq {
+			pairs = append(pairs, Pair[K, V]{k, v})
+		}
+		swap := func(i, j int) { pairs[i], pairs[j] = pairs[j], pairs[i] }
+		if r == nil {
+			rand.Shuffle(len(pairs), swap)
+		} else {
+			r.Shuffle(len(pairs), swap)
+		}
+		for _, p := range pairs {
+			if !yield(p.Key, p.Value) {
+				return
+			}
+		}
+	}
+}
+
+// FirstError returns the first non-nil error of seq, stopping seq right
+// there, or nil once seq is exhausted without one
+func FirstError[V any](seq iter.Seq2[V, error]) error {
+	for _, err := range seq {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StepBy yields the first value of seq and then every step-th one after it.
+// It panics if step is not positive
+func StepBy[V any](seq iter.Seq[V], step int) iter.Seq[V] {
+	if step <= 0 {
+		panic(fmt.Sprintf("StepBy: step must be positive, got %d", step))
+	}
+	return func(yield func(V) bool) {
+		i := 0
+		for v := range seq {
+			if i%step == 0 && !yield(v) {
+				return
+			}
+			i++
+		}
+	}
+}
+
+// Sample picks k pairs of seq uniformly at random in a single pass, keeping
+// only the k candidates in memory (reservoir sampling). A seeded r gives a
+// reproducible sample; a nil r uses the global source. If seq has fewer than
+// k pairs, all of them are returned
+func Sample[K, V any](seq iter.Seq2[K, V], k int, r *rand.Rand) []Pair[K, V] {
+	if k <= 0 {
+		return nil
+	}
+	intN := rand.IntN
+	if r != nil {
+		intN = r.IntN
+	}
+
+	reservoir := make([]Pair[K, V], 0, k)
+	n := 0
+	for key, v := range seq {
+		n++
+		if len(reservoir) < k {
+			reservoir = append(reservoir, Pair[K, V]{key, v})
+			continue
+		}
+		if j := intN(n); j < k {
+			reservoir[j] = Pair[K, V]{key, v}
+		}
+	}
+	return reservoir
+}
+
+// MapKeys yields the pairs of seq with f applied to their keys
+func MapKeys[K, K2, V any](seq iter.Seq2[K, V], f func(K) K2) iter.Seq2[K2, V] {
+	return func(yield func(K2, V) bool) {
+		for k, v := range seq {
+			if !yield(f(k), v) {
+				return
+			}
+		}
+	}
+}
+
+// SortedEntries drains seq and returns its pairs ordered by key, a stable
+// snapshot of e.g. maps.All
+func SortedEntries[K cmp.Ordered, V any](seq iter.Seq2[K, V]) []Pair[K, V] {
+	var entries []Pair[K, V]
+	for k, v := range SortedByKey(seq) {
+		entries = append(entries, Pair[K, V]{k, v})
+	}
+	return entries
+}
+
+// CollectN collects at most max values of seq, then stops it; a safe way to
+// materialize a source that may be infinite. The result grows as values
+// arrive, so a generous max costs nothing up front
+func CollectN[V any](seq iter.Seq[V], max int) []V {
+	if max <= 0 {
+		return nil
+	}
+	var values []V
+	for v := range seq {
+		values = append(values, v)
+		if len(values) == max {
+			break
+		}
+	}
+	return values
+}
+
+// IndexOf returns the 0-based position of the first pair of seq satisfying
+// pred, stopping seq right there, or -1 if none does
+func IndexOf[K, V any](seq iter.Seq2[K, V], pred func(K, V) bool) int {
+	i := 0
+	for k, v := range seq {
+		if pred(k, v) {
+			return i
+		}
+		i++
+	}
+	return -1
+}
+
+// RunningMax yields, for every pair of seq, its key along with the largest
+// value seen so far, which makes the values non-decreasing
+func RunningMax[K any, V cmp.Ordered](seq iter.Seq2[K, V]) iter.Seq2[K, V] {
+	return running(seq, func(best, v V) bool { return v > best })
+}
+
+// RunningMin is RunningMax keeping the smallest value seen so far
+func RunningMin[K any, V cmp.Ordered](seq iter.Seq2[K, V]) iter.Seq2[K, V] {
+	return running(seq, func(best, v V) bool { return v < best })
+}
+
+func running[K any, V cmp.Ordered](seq iter.Seq2[K, V], better func(best, v V) bool) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		var best V
+		first := true
+		for k, v := range seq {
+			if first || better(best, v) {
+				best, first = v, false
+			}
+			if !yield(k, best) {
+				return
+			}
+		}
+	}
+}
+
+// IgnoreErrors yields the values of seq paired with a nil error and silently
+// drops the rest. The errors are lost for good, so only use it where
+// best-effort processing is really what is wanted
+func IgnoreErrors[V any](seq iter.Seq2[V, error]) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for v, err := range seq {
+			if err != nil {
+				continue
+			}
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Join concatenates the strings of seq with sep between them
+func Join(seq iter.Seq[string], sep string) string {
+	var b strings.Builder
+	first := true
+	for s := range seq {
+		if !first {
+			b.WriteString(sep)
+		}
+		first = false
+		b.WriteString(s)
+	}
+	return b.String()
+}
+
+// DedupErrors collapses runs of consecutive equal errors of seq into their
+// first pair. Two errors are equal if errors.Is matches them or their
+// messages are the same. Pairs with a nil error always pass and end a run
+func DedupErrors[K any](seq iter.Seq2[K, error]) iter.Seq2[K, error] {
+	return func(yield func(K, error) bool) {
+		var prev error
+		for k, err := range seq {
+			if err != nil && prev != nil && (errors.Is(err, prev) || err.Error() == prev.Error()) {
+				continue
+			}
+			prev = err
+			if !yield(k, err) {
+				return
+			}
+		}
+	}
+}
+
+// Mismatches tells AssertValues what to do with a value of the wrong type
+type Mismatches int
+
+const (
+	SkipMismatches   Mismatches = iota // the pair is dropped
+	RejectMismatches                   // iteration stops with an error
+)
+
+// AssertValues yields the pairs of seq with their values asserted to V.
+// Pairs whose value is not a V are handled according to mismatches; when
+// rejected, the returned function reports the error afterwards (nil if the
+// last range ended without one)
+func AssertValues[K, V any](seq iter.Seq2[K, any], mismatches Mismatches) (iter.Seq2[K, V], func() error) {
+	var lastErr error
+	values := func(yield func(K, V) bool) {
+		lastErr = nil
+		for k, a := range seq {
+			v, ok := a.(V)
+			if !ok {
+				if mismatches == RejectMismatches {
+					lastErr = fmt.Errorf("value for key %v: got %T, want %v", k, a, reflect.TypeFor[V]())
+					return
+				}
+				continue
+			}
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+	return values, func() error { return lastErr }
+}
+
+// Progress passes seq through unchanged and calls report with the running
+// count after every every-th value, e.g. to log "processed N lines".
+// A non-positive every never reports
+func Progress[V any](seq iter.Seq[V], every int, report func(count int)) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		count := 0
+		for v := range seq {
+			if !yield(v) {
+				return
+			}
+			count++
+			if every > 0 && count%every == 0 {
+				report(count)
+			}
+		}
+	}
+}
@@ -0,0 +1,1451 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"iter"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// openFiles returns the number of file descriptors the test process holds,
+// skipping the test where /proc is not available
+func openFiles(t *testing.T) int {
+	t.Helper()
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skipf("can't count open files: %v", err)
+	}
+	return len(entries)
+}
+
+func TestDrainClosesFile(t *testing.T) {
+	r := NewFileReader(writeTestFile(t, "a\nb\nc\n"))
+	before := openFiles(t)
+
+	Drain2(r.All())
+	if after := openFiles(t); after != before {
+		t.Errorf("%d open files after Drain2, want %d", after, before)
+	}
+
+	n := 0
+	lines := func(yield func(string) bool) {
+		for line := range r.All() {
+			n++
+			if !yield(line) {
+				return
+			}
+		}
+	}
+	Drain(iter.Seq[string](lines))
+	if n != 3 {
+		t.Errorf("drained %d lines, want 3", n)
+	}
+	if after := openFiles(t); after != before {
+		t.Errorf("%d open files after Drain, want %d", after, before)
+	}
+}
+
+func TestDrainRunsCleanup(t *testing.T) {
+	cleaned := false
+	seq := func(yield func(int) bool) {
+		defer func() { cleaned = true }()
+		for i := range 3 {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+	Drain(seq)
+	if !cleaned {
+		t.Error("Drain returned before the source cleaned up")
+	}
+}
+
+// captureStdout runs f and returns what it printed to os.Stdout, such as the
+// generator's "Received stop" and "Limit reached" messages
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+	f()
+	w.Close()
+	return <-out
+}
+
+func TestLast(t *testing.T) {
+	if v, ok := Last(slices.Values([]int(nil))); ok {
+		t.Errorf("Last of empty = %d, true; want false", v)
+	}
+	if v, ok := Last(slices.Values([]int{7})); !ok || v != 7 {
+		t.Errorf("Last of single = %d, %v; want 7, true", v, ok)
+	}
+	if _, _, ok := Last2(slices.All([]string(nil))); ok {
+		t.Error("Last2 of empty reported a pair")
+	}
+	if i, v, ok := Last2(slices.All([]string{"x"})); !ok || i != 0 || v != "x" {
+		t.Errorf("Last2 of single = %d, %q, %v; want 0, \"x\", true", i, v, ok)
+	}
+}
+
+func TestLastGeneratorValue(t *testing.T) {
+	var want []int
+	captureStdout(t, func() {
+		for _, v := range NewRandomValuesGenerator(WithSeed(1)).All() {
+			want = append(want, v)
+		}
+	})
+
+	var (
+		i, v int
+		ok   bool
+	)
+	out := captureStdout(t, func() {
+		i, v, ok = Last2(NewRandomValuesGenerator(WithSeed(1)).All())
+	})
+	if !ok || i != limit-1 || v != want[limit-1] {
+		t.Errorf("Last2 = %d, %d, %v; want %d, %d, true", i, v, ok, limit-1, want[limit-1])
+	}
+	if !strings.Contains(out, "Limit reached") {
+		t.Errorf("generator printed %q, want it to run to its limit", out)
+	}
+}
+
+func TestPartition(t *testing.T) {
+	lines := []string{"ERROR disk", "INFO boot", "INFO ready", "ERROR net", "INFO done"}
+	isError := func(_ int, line string) bool { return strings.HasPrefix(line, "ERROR") }
+
+	collect := func(seq iter.Seq2[int, string]) []int {
+		var idx []int
+		for i := range seq {
+			idx = append(idx, i)
+		}
+		return idx
+	}
+
+	t.Run("matched first", func(t *testing.T) {
+		matched, unmatched := Partition(slices.All(lines), isError)
+		errs, infos := collect(matched), collect(unmatched)
+		if !slices.Equal(errs, []int{0, 3}) || !slices.Equal(infos, []int{1, 2, 4}) {
+			t.Errorf("got %v and %v, want [0 3] and [1 2 4]", errs, infos)
+		}
+	})
+	t.Run("unmatched first", func(t *testing.T) {
+		matched, unmatched := Partition(slices.All(lines), isError)
+		infos, errs := collect(unmatched), collect(matched)
+		if !slices.Equal(errs, []int{0, 3}) || !slices.Equal(infos, []int{1, 2, 4}) {
+			t.Errorf("got %v and %v, want [0 3] and [1 2 4]", errs, infos)
+		}
+	})
+	t.Run("disjoint", func(t *testing.T) {
+		matched, unmatched := Partition(slices.All(lines), isError)
+		seen := make(map[int]int)
+		for i, line := range matched {
+			if !isError(i, line) {
+				t.Errorf("matched side got %q", line)
+			}
+			seen[i]++
+		}
+		for i, line := range unmatched {
+			if isError(i, line) {
+				t.Errorf("unmatched side got %q", line)
+			}
+			seen[i]++
+		}
+		for i := range lines {
+			if seen[i] != 1 {
+				t.Errorf("line %d seen %d times, want once", i, seen[i])
+			}
+		}
+	})
+}
+
+func TestNth(t *testing.T) {
+	var want []int
+	captureStdout(t, func() {
+		for _, v := range NewRandomValuesGenerator(WithSeed(42)).All() {
+			want = append(want, v)
+		}
+	})
+
+	var (
+		v  int
+		ok bool
+	)
+	values := func(yield func(int) bool) {
+		for _, v := range NewRandomValuesGenerator(WithSeed(42)).All() {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+	out := captureStdout(t, func() { v, ok = Nth(values, 2) })
+	if !ok || v != want[2] {
+		t.Errorf("Nth(2) = %d, %v; want %d, true", v, ok, want[2])
+	}
+	if out != "Received stop\n" {
+		t.Errorf("generator printed %q, want it stopped early", out)
+	}
+
+	if _, ok := Nth(slices.Values([]int{1, 2, 3}), 3); ok {
+		t.Error("Nth past the end reported a value")
+	}
+	if _, ok := Nth(slices.Values([]int{1, 2, 3}), -1); ok {
+		t.Error("Nth(-1) reported a value")
+	}
+}
+
+func TestFirst(t *testing.T) {
+	words := []string{"apple", "banana", "cherry", "date"}
+	startsWith := func(prefix string) func(int, string) bool {
+		return func(_ int, w string) bool { return strings.HasPrefix(w, prefix) }
+	}
+
+	if i, w, ok := First(slices.All(words), startsWith("c")); !ok || i != 2 || w != "cherry" {
+		t.Errorf("First = %d, %q, %v; want 2, \"cherry\", true", i, w, ok)
+	}
+	if i, w, ok := First(slices.All(words), startsWith("z")); ok || i != 0 || w != "" {
+		t.Errorf("First without a match = %d, %q, %v; want zero values and false", i, w, ok)
+	}
+
+	if !Any(slices.All(words), startsWith("b")) || Any(slices.All(words), startsWith("z")) {
+		t.Error("Any disagrees with the words")
+	}
+	long := func(_ int, w string) bool { return len(w) >= 4 }
+	if !All(slices.All(words), long) || All(slices.All(words), startsWith("a")) {
+		t.Error("All disagrees with the words")
+	}
+}
+
+func TestFirstStopsGenerator(t *testing.T) {
+	g := NewRandomValuesGenerator(WithSeed(7))
+	var i int
+	out := captureStdout(t, func() {
+		i, _, _ = First(g.All(), func(i, _ int) bool { return i == 4 })
+	})
+	if i != 4 || out != "Received stop\n" {
+		t.Errorf("match in the middle: index %d, printed %q; want 4 and \"Received stop\"", i, out)
+	}
+
+	out = captureStdout(t, func() {
+		_, _, ok := First(g.All(), func(_, v int) bool { return v >= 100 })
+		if ok {
+			t.Error("First found a value out of range")
+		}
+	})
+	if out != "Limit reached\n" {
+		t.Errorf("no match: printed %q, want \"Limit reached\"", out)
+	}
+}
+
+func TestCache(t *testing.T) {
+	pulls := make(map[int]int)
+	source := func(yield func(int, string) bool) {
+		for i, s := range []string{"a", "b", "c", "d"} {
+			pulls[i]++
+			if !yield(i, s) {
+				return
+			}
+		}
+	}
+	cached := Cache(source)
+
+	// A partial range caches what it saw...
+	for i := range cached {
+		if i == 1 {
+			break
+		}
+	}
+	// ...and later ranges replay it, then pull the rest
+	for range 2 {
+		var got []string
+		for _, s := range cached {
+			got = append(got, s)
+		}
+		if !slices.Equal(got, []string{"a", "b", "c", "d"}) {
+			t.Errorf("got %q, want [a b c d]", got)
+		}
+	}
+	for i := range 4 {
+		if pulls[i] != 1 {
+			t.Errorf("element %d pulled %d times, want once", i, pulls[i])
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	sum := func(acc, v int) int { return acc + v }
+	got := slices.Collect(Scan(slices.Values([]int{1, 2, 3, 4, 5}), 0, sum))
+	if want := []int{1, 3, 6, 10, 15}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	var partial []int
+	for acc := range Scan(slices.Values([]int{1, 2, 3, 4, 5}), 0, sum) {
+		partial = append(partial, acc)
+		if acc >= 6 {
+			break
+		}
+	}
+	if want := []int{1, 3, 6}; !slices.Equal(partial, want) {
+		t.Errorf("early stop: got %v, want %v", partial, want)
+	}
+}
+
+// generatorValues returns the values of a seeded generator with the given
+// limit, discarding what it prints
+func generatorValues(t *testing.T, seed uint64, n int) []int {
+	t.Helper()
+	var values []int
+	captureStdout(t, func() {
+		for _, v := range NewRandomValuesGenerator(WithSeed(seed), WithLimit(n)).All() {
+			values = append(values, v)
+		}
+	})
+	return values
+}
+
+// generatorSeq is the values of a seeded generator as an iter.Seq
+func generatorSeq(seed uint64, n int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for _, v := range NewRandomValuesGenerator(WithSeed(seed), WithLimit(n)).All() {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+func TestTakeWhile(t *testing.T) {
+	values := generatorValues(t, 3, 100)
+	end := slices.IndexFunc(values, func(v int) bool { return v >= 90 })
+	if end < 0 {
+		t.Fatal("fixture has no value >= 90")
+	}
+
+	var got []int
+	out := captureStdout(t, func() {
+		got = slices.Collect(TakeWhile(generatorSeq(3, 100), func(v int) bool { return v < 90 }))
+	})
+	if !slices.Equal(got, values[:end]) {
+		t.Errorf("got %v, want %v", got, values[:end])
+	}
+	if out != "Received stop\n" {
+		t.Errorf("generator printed %q, want it stopped at the first value >= 90", out)
+	}
+}
+
+func TestDropWhile(t *testing.T) {
+	values := generatorValues(t, 3, 100)
+	start := slices.IndexFunc(values, func(v int) bool { return v%2 != 0 })
+	if start < 0 {
+		t.Fatal("fixture has no odd value")
+	}
+
+	var got []int
+	captureStdout(t, func() {
+		got = slices.Collect(DropWhile(generatorSeq(3, 100), func(v int) bool { return v%2 == 0 }))
+	})
+	if !slices.Equal(got, values[start:]) {
+		t.Errorf("got %v, want %v", got, values[start:])
+	}
+}
+
+func TestCompactBlankLines(t *testing.T) {
+	r := NewFileReader(writeTestFile(t, "first\n\nsecond\n\n\nthird\n"))
+	lines, lastErr := UntilError(r.All())
+	got := slices.Collect(Compact(lines))
+	if err := lastErr(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"first", "second", "third"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if got := slices.Collect(Compact(slices.Values([]int{0, 1, 0, 2}))); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("ints: got %v, want [1 2]", got)
+	}
+}
+
+func TestUntilError(t *testing.T) {
+	errBad := errors.New("bad third")
+	stopped := false
+	source := func(yield func(string, error) bool) {
+		defer func() { stopped = true }()
+		for _, p := range []Pair[string, error]{{"a", nil}, {"b", nil}, {"", errBad}, {"d", nil}} {
+			if !yield(p.Key, p.Value) {
+				return
+			}
+		}
+	}
+
+	values, lastErr := UntilError(source)
+	if got := slices.Collect(values); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("got %q, want [a b]", got)
+	}
+	if err := lastErr(); !errors.Is(err, errBad) {
+		t.Errorf("lastErr() = %v, want %v", err, errBad)
+	}
+	if !stopped {
+		t.Error("the source was not stopped at the error")
+	}
+
+	stopped = false
+	for range values {
+		break
+	}
+	if !stopped {
+		t.Error("an early break did not stop the source")
+	}
+	if err := lastErr(); err != nil {
+		t.Errorf("after an early break lastErr() = %v, want nil", err)
+	}
+}
+
+func TestSwap(t *testing.T) {
+	var pairs, swapped []Pair[int, int]
+	captureStdout(t, func() {
+		for i, v := range NewRandomValuesGenerator(WithSeed(5)).All() {
+			pairs = append(pairs, Pair[int, int]{i, v})
+		}
+		for v, i := range Swap(NewRandomValuesGenerator(WithSeed(5)).All()) {
+			swapped = append(swapped, Pair[int, int]{v, i})
+		}
+	})
+	if len(swapped) != len(pairs) {
+		t.Fatalf("got %d pairs, want %d", len(swapped), len(pairs))
+	}
+	for i, p := range pairs {
+		if swapped[i] != (Pair[int, int]{p.Value, p.Key}) {
+			t.Errorf("pair %d = %v, want %v", i, swapped[i], Pair[int, int]{p.Value, p.Key})
+		}
+	}
+
+	out := captureStdout(t, func() {
+		for range Swap(NewRandomValuesGenerator(WithSeed(5)).All()) {
+			break
+		}
+	})
+	if out != "Received stop\n" {
+		t.Errorf("early break printed %q, want the generator stopped", out)
+	}
+}
+
+func TestUnzip2(t *testing.T) {
+	keys, values := Unzip2(slices.All([]string{"x", "y", "z"}))
+	if !slices.Equal(keys, []int{0, 1, 2}) || !slices.Equal(values, []string{"x", "y", "z"}) {
+		t.Errorf("got %v and %q, want [0 1 2] and [x y z]", keys, values)
+	}
+
+	var pairs []Pair[int, int]
+	captureStdout(t, func() {
+		for i, v := range NewRandomValuesGenerator(WithSeed(9)).All() {
+			pairs = append(pairs, Pair[int, int]{i, v})
+		}
+		keys, ints := Unzip2(NewRandomValuesGenerator(WithSeed(9)).All())
+		if len(keys) != len(pairs) || len(ints) != len(pairs) {
+			t.Fatalf("got %d keys and %d values, want %d of each", len(keys), len(ints), len(pairs))
+		}
+		for i, p := range pairs {
+			if keys[i] != p.Key || ints[i] != p.Value {
+				t.Errorf("index %d: got %d, %d; want %d, %d", i, keys[i], ints[i], p.Key, p.Value)
+			}
+		}
+	})
+}
+
+func TestZipSeq(t *testing.T) {
+	stopped := 0
+	counted := func(seq iter.Seq[int]) iter.Seq[int] {
+		return func(yield func(int) bool) {
+			defer func() { stopped++ }()
+			seq(yield)
+		}
+	}
+
+	var got []Pair[int, string]
+	for n, s := range ZipSeq(counted(slices.Values([]int{1, 2, 3})), slices.Values([]string{"a", "b"})) {
+		got = append(got, Pair[int, string]{n, s})
+	}
+	if want := []Pair[int, string]{{1, "a"}, {2, "b"}}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if stopped != 1 {
+		t.Errorf("the longer source was stopped %d times, want once", stopped)
+	}
+}
+
+func TestCycle(t *testing.T) {
+	ranged := 0
+	source := func(yield func(int) bool) {
+		ranged++
+		for i := range 3 {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+
+	got := CollectN(Cycle(source), 7)
+	if want := []int{0, 1, 2, 0, 1, 2, 0}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if ranged != 1 {
+		t.Errorf("source ranged %d times, want once", ranged)
+	}
+	if got := CollectN(Cycle(slices.Values([]int(nil))), 3); len(got) != 0 {
+		t.Errorf("empty source: got %v, want nothing", got)
+	}
+}
+
+func TestFromSlice(t *testing.T) {
+	s := []string{"a", "b", "c"}
+	if got := slices.Collect(FromSlice(s)); !slices.Equal(got, s) {
+		t.Errorf("got %q, want %q", got, s)
+	}
+	if got := CollectN(FromSlice(s), 2); !slices.Equal(got, s[:2]) {
+		t.Errorf("early break: got %q, want %q", got, s[:2])
+	}
+}
+
+func TestFromMapValues(t *testing.T) {
+	m := map[string]int{"a": 1, "b": 2, "c": 3}
+	got := slices.Sorted(FromMapValues(m))
+	if want := slices.Sorted(maps.Values(m)); !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := CollectN(FromMapValues(m), 2); len(got) != 2 {
+		t.Errorf("early break: got %v, want two values", got)
+	}
+}
+
+func TestPartitionBy(t *testing.T) {
+	values := generatorValues(t, 11, 50)
+
+	captureStdout(t, func() {
+		high, low := PartitionBy(NewRandomValuesGenerator(WithSeed(11), WithLimit(50)).All(), func(_, v int) bool { return v >= 50 })
+		got := make([]int, len(values))
+		seen := 0
+		for i, v := range low {
+			if v >= 50 {
+				t.Errorf("low side got %d", v)
+			}
+			got[i] = v
+			seen++
+		}
+		for i, v := range high {
+			if v < 50 {
+				t.Errorf("high side got %d", v)
+			}
+			got[i] = v
+			seen++
+		}
+		if seen != len(values) || !slices.Equal(got, values) {
+			t.Errorf("the partitions hold %d values %v, want the original %v", seen, got, values)
+		}
+	})
+}
+
+func TestReindex(t *testing.T) {
+	words := []string{"a", "b", "c", "d", "e"}
+	even := filter2(slices.All(words), func(i int, _ string) bool { return i%2 == 0 })
+
+	var (
+		keys []int
+		got  []string
+	)
+	for i, w := range Reindex(even) {
+		keys = append(keys, i)
+		got = append(got, w)
+	}
+	if !slices.Equal(keys, []int{0, 1, 2}) || !slices.Equal(got, []string{"a", "c", "e"}) {
+		t.Errorf("got %v and %q, want [0 1 2] and [a c e]", keys, got)
+	}
+}
+
+func TestTap2(t *testing.T) {
+	var tapped, yielded []Pair[int, string]
+	seq := Tap2(slices.All([]string{"a", "b", "c", "d"}), func(i int, s string) {
+		tapped = append(tapped, Pair[int, string]{i, s})
+	})
+	for i, s := range seq {
+		if len(tapped) != len(yielded)+1 {
+			t.Fatalf("pair %d: tapped %d pairs, want fn called right before yield", i, len(tapped))
+		}
+		yielded = append(yielded, Pair[int, string]{i, s})
+		if i == 2 {
+			break
+		}
+	}
+	if !slices.Equal(tapped, yielded) {
+		t.Errorf("tapped %v, yielded %v", tapped, yielded)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a, b []int
+		want bool
+	}{
+		{[]int{1, 2, 3}, []int{1, 2, 3}, true},
+		{nil, nil, true},
+		{[]int{1, 2, 3}, []int{1, 9, 3}, false},
+		{[]int{1, 2}, []int{1, 2, 3}, false},
+		{[]int{1, 2, 3}, []int{1, 2}, false},
+	}
+	for _, tt := range tests {
+		if got := Equal(slices.Values(tt.a), slices.Values(tt.b)); got != tt.want {
+			t.Errorf("Equal(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got := Equal2(slices.All(tt.a), slices.All(tt.b)); got != tt.want {
+			t.Errorf("Equal2(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	// Equal2 compares keys too
+	if Equal2(Reindex(slices.All([]int{5})), OffsetIndex(slices.All([]int{5}), 1)) {
+		t.Error("Equal2 ignored a key difference")
+	}
+}
+
+func TestEqualStopsBoth(t *testing.T) {
+	stopped := 0
+	infinite := func(yield func(int) bool) {
+		defer func() { stopped++ }()
+		for i := 0; ; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+	if Equal(infinite, slices.Values([]int{0, 1, 7})) {
+		t.Error("Equal reported a difference as equal")
+	}
+	if stopped != 1 {
+		t.Errorf("the infinite source was stopped %d times, want once", stopped)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	values := generatorValues(t, 2, 200)
+	want := make([]int, 10)
+	for _, v := range values {
+		want[v/10]++
+	}
+
+	var got []int
+	captureStdout(t, func() {
+		got = Histogram(NewRandomValuesGenerator(WithSeed(2), WithLimit(200)).All(), 10, 0, 100)
+	})
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Out-of-range values land in the outer buckets
+	got = Histogram(slices.All([]int{-5, 0, 4, 5, 9, 10, 50}), 2, 0, 10)
+	if want := []int{3, 4}; !slices.Equal(got, want) {
+		t.Errorf("clamping: got %v, want %v", got, want)
+	}
+	if got := Histogram(slices.All([]int{1}), 0, 0, 10); got != nil {
+		t.Errorf("no buckets: got %v, want nil", got)
+	}
+}
+
+func TestHistogramExtremes(t *testing.T) {
+	tests := []struct {
+		name     string
+		v        int
+		min, max int
+		want     int
+	}{
+		{"midpoint of a huge range", 1 << 61, 0, 1 << 62, 5},
+		{"zero across all of int", 0, math.MinInt, math.MaxInt, 5},
+		{"bottom of all of int", math.MinInt, math.MinInt, math.MaxInt, 0},
+		{"top of all of int", math.MaxInt - 1, math.MinInt, math.MaxInt, 9},
+		{"just below zero", -1, math.MinInt, math.MaxInt, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Histogram(slices.All([]int{tt.v}), 10, tt.min, tt.max)
+			want := make([]int, 10)
+			want[tt.want] = 1
+			if !slices.Equal(got, want) {
+				t.Errorf("got %v, want %d in bucket %d", got, tt.v, tt.want)
+			}
+		})
+	}
+}
+
+func TestTap(t *testing.T) {
+	seen := make(map[int]int)
+	got := slices.Collect(Tap(slices.Values([]int{3, 1, 2}), func(v int) { seen[v]++ }))
+	if !slices.Equal(got, []int{3, 1, 2}) {
+		t.Errorf("got %v, want the stream unchanged", got)
+	}
+	for _, v := range got {
+		if seen[v] != 1 {
+			t.Errorf("%d tapped %d times, want once", v, seen[v])
+		}
+	}
+
+	var tapped []int
+	stopped := false
+	source := func(yield func(int) bool) {
+		defer func() { stopped = true }()
+		for i := 0; ; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+	got = CollectN(Tap(source, func(v int) { tapped = append(tapped, v) }), 3)
+	if !stopped || !slices.Equal(tapped, got) {
+		t.Errorf("early stop: tapped %v for %v, source stopped: %v", tapped, got, stopped)
+	}
+}
+
+func TestMapErr(t *testing.T) {
+	var (
+		values []int
+		failed []int
+	)
+	i := 0
+	for n, err := range MapErr(slices.Values([]string{"1", "x", "3", "", "5"}), strconv.Atoi) {
+		if err != nil {
+			if n != 0 {
+				t.Errorf("input %d: got %d with the error, want 0", i, n)
+			}
+			var numErr *strconv.NumError
+			if !errors.As(err, &numErr) {
+				t.Errorf("input %d: got %v, want a *strconv.NumError", i, err)
+			}
+			failed = append(failed, i)
+		} else {
+			values = append(values, n)
+		}
+		i++
+	}
+	if !slices.Equal(values, []int{1, 3, 5}) || !slices.Equal(failed, []int{1, 3}) {
+		t.Errorf("got values %v and failures at %v, want [1 3 5] and [1 3]", values, failed)
+	}
+}
+
+// pairSeq yields pairs in order, for sources a test spells out in full
+func pairSeq[K, V any](pairs []Pair[K, V]) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, p := range pairs {
+			if !yield(p.Key, p.Value) {
+				return
+			}
+		}
+	}
+}
+
+// collectPairs drains seq into a slice of its pairs
+func collectPairs[K, V any](seq iter.Seq2[K, V]) []Pair[K, V] {
+	var pairs []Pair[K, V]
+	for k, v := range seq {
+		pairs = append(pairs, Pair[K, V]{k, v})
+	}
+	return pairs
+}
+
+// naturals yields 0, 1, 2, ... for as long as it is ranged
+func naturals() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i := 0; yield(i); i++ {
+		}
+	}
+}
+
+// probe records what a consumer did with a source: how many values it
+// pulled and whether it stopped the source before the source ran out
+type probe struct {
+	pulled  int
+	stopped bool
+}
+
+func probeSeq[V any](p *probe, seq iter.Seq[V]) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for v := range seq {
+			p.pulled++
+			if !yield(v) {
+				p.stopped = true
+				return
+			}
+		}
+	}
+}
+
+func probeSeq2[K, V any](p *probe, seq iter.Seq2[K, V]) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for k, v := range seq {
+			p.pulled++
+			if !yield(k, v) {
+				p.stopped = true
+				return
+			}
+		}
+	}
+}
+
+func TestProduct(t *testing.T) {
+	type (
+		left  = Pair[int, string]
+		right = Pair[int, int]
+	)
+	a := slices.All([]string{"x", "y"})
+	b := slices.All([]int{10, 20, 30})
+
+	var got []string
+	for p := range Product(a, b) {
+		got = append(got, fmt.Sprintf("%s%d", p.Key.Value, p.Value.Value))
+	}
+	want := []string{"x10", "x20", "x30", "y10", "y20", "y30"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	var pa, pb probe
+	first := CollectN(Product(probeSeq2(&pa, a), probeSeq2(&pb, b)), 1)
+	if want := (Pair[left, right]{left{0, "x"}, right{0, 10}}); len(first) != 1 || first[0] != want {
+		t.Errorf("early stop: got %v, want [%v]", first, want)
+	}
+	if !pa.stopped || pb.pulled != 3 || pb.stopped {
+		t.Errorf("early stop: a stopped %t, b pulled %d and stopped %t; want a stopped and b read in full", pa.stopped, pb.pulled, pb.stopped)
+	}
+}
+
+func TestTally(t *testing.T) {
+	var counts map[int]int
+	captureStdout(t, func() {
+		counts = Tally(generatorSeq(4, 500))
+	})
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	if total != 500 {
+		t.Errorf("counts add up to %d, want 500", total)
+	}
+
+	values := generatorValues(t, 4, 500)
+	want := 0
+	for _, v := range values {
+		if v == values[0] {
+			want++
+		}
+	}
+	if counts[values[0]] != want {
+		t.Errorf("count of %d = %d, want %d", values[0], counts[values[0]], want)
+	}
+
+	words := Tally(slices.Values([]string{"a", "b", "a"}))
+	if !maps.Equal(words, map[string]int{"a": 2, "b": 1}) {
+		t.Errorf("got %v, want map[a:2 b:1]", words)
+	}
+}
+
+func TestFrequencies(t *testing.T) {
+	r := NewFileReader(writeTestFile(t, "the cat\nthe dog and the cat\n"))
+	// Every word keyed by itself, with the number of the line it is on
+	words := func(yield func(string, int) bool) {
+		n := 0
+		for line, err := range r.All() {
+			if err != nil {
+				t.Fatal(err)
+			}
+			n++
+			for _, w := range strings.Fields(line) {
+				if !yield(w, n) {
+					return
+				}
+			}
+		}
+	}
+
+	want := map[string]int{"the": 3, "cat": 2, "dog": 1, "and": 1}
+	if got := Frequencies(words); !maps.Equal(got, want) {
+		t.Errorf("Frequencies = %v, want %v", got, want)
+	}
+	if got := ValueFrequencies(words); !maps.Equal(got, map[int]int{1: 2, 2: 5}) {
+		t.Errorf("ValueFrequencies = %v, want map[1:2 2:5]", got)
+	}
+}
+
+func TestChunkByKey(t *testing.T) {
+	seq := pairSeq([]Pair[int, string]{{1, "a"}, {1, "b"}, {2, "c"}, {1, "d"}})
+
+	got := collectPairs(ChunkByKey(seq))
+	want := []Pair[int, []string]{{1, []string{"a", "b"}}, {2, []string{"c"}}, {1, []string{"d"}}}
+	equal := func(a, b Pair[int, []string]) bool { return a.Key == b.Key && slices.Equal(a.Value, b.Value) }
+	if !slices.EqualFunc(got, want, equal) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for k, vs := range ChunkByKey(seq) {
+		if k != 1 || !slices.Equal(vs, []string{"a", "b"}) {
+			t.Errorf("first group = %d, %q; want 1, [a b]", k, vs)
+		}
+		break
+	}
+}
+
+func TestDrain2WithStop(t *testing.T) {
+	var indices []int
+	out := captureStdout(t, func() {
+		Drain2WithStop(NewRandomValuesGenerator(WithSeed(1)).All(), func(i, _ int) bool {
+			indices = append(indices, i)
+			return len(indices) < 5
+		})
+	})
+	if !slices.Equal(indices, []int{0, 1, 2, 3, 4}) {
+		t.Errorf("handled %v, want the first five pairs", indices)
+	}
+	if out != "Received stop\n" {
+		t.Errorf("generator printed %q, want it stopped", out)
+	}
+
+	out = captureStdout(t, func() {
+		Drain2WithStop(NewRandomValuesGenerator(WithLimit(3)).All(), func(int, int) bool { return true })
+	})
+	if out != "Limit reached\n" {
+		t.Errorf("draining to the end printed %q, want \"Limit reached\"", out)
+	}
+}
+
+func TestTakeDistinct(t *testing.T) {
+	letters := Swap(slices.All([]string{"a", "a", "b", "a", "c", "b", "d", "e"}))
+
+	var p probe
+	got := collectPairs(TakeDistinct(probeSeq2(&p, letters), 3))
+	if want := []Pair[string, int]{{"a", 0}, {"b", 2}, {"c", 4}}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if p.pulled != 5 || !p.stopped {
+		t.Errorf("pulled %d pairs, stopped %t; want the source stopped at the third distinct key", p.pulled, p.stopped)
+	}
+
+	if got := collectPairs(TakeDistinct(letters, 10)); len(got) != 5 {
+		t.Errorf("with n past the distinct keys: got %v, want all five", got)
+	}
+}
+
+// stopCounter wraps sequences so that it can tell how many of them were
+// stopped or ran out
+type stopCounter struct {
+	stopped int
+}
+
+func (c *stopCounter) wrap(seq iter.Seq[int]) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		defer func() { c.stopped++ }()
+		seq(yield)
+	}
+}
+
+func TestInterleave(t *testing.T) {
+	a := slices.Values([]int{1, 2})
+	b := slices.Values([]int{10, 20, 30, 40})
+	c := slices.Values([]int{100})
+
+	got := slices.Collect(Interleave(a, b, c))
+	if want := []int{1, 10, 100, 2, 20, 30, 40}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	var counter stopCounter
+	got = CollectN(Interleave(counter.wrap(a), counter.wrap(b), counter.wrap(c)), 4)
+	if !slices.Equal(got, []int{1, 10, 100, 2}) || counter.stopped != 3 {
+		t.Errorf("early stop: got %v with %d sources stopped, want [1 10 100 2] and all 3", got, counter.stopped)
+	}
+}
+
+func TestInterleave2(t *testing.T) {
+	letters := slices.All([]string{"a", "b"})
+	digits := slices.All([]string{"1", "2", "3"})
+
+	var got []string
+	for _, v := range Interleave2(letters, digits) {
+		got = append(got, v)
+	}
+	if want := []string{"a", "1", "b", "2", "3"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	var pl, pd probe
+	for _, v := range Interleave2(probeSeq2(&pl, letters), probeSeq2(&pd, digits)) {
+		if v == "b" {
+			break
+		}
+	}
+	if !pl.stopped || !pd.stopped {
+		t.Errorf("early stop: letters stopped %t, digits stopped %t; want both", pl.stopped, pd.stopped)
+	}
+}
+
+func TestDistinctWindow(t *testing.T) {
+	// With a window of 2, the second "a" is a close repeat and dropped, while
+	// the third comes after "c" pushed "a" out and is yielded again
+	input := []string{"a", "b", "a", "c", "d", "a", "d"}
+	got := slices.Collect(DistinctWindow(slices.Values(input), 2))
+	if want := []string{"a", "b", "c", "d", "a"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if got := slices.Collect(DistinctWindow(slices.Values(input), 0)); !slices.Equal(got, input) {
+		t.Errorf("window 0: got %q, want the input unchanged", got)
+	}
+}
+
+func TestMemoize(t *testing.T) {
+	runs := 0
+	source := func(yield func(int) bool) {
+		runs++
+		for i := range 4 {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+	memo := Memoize(source)
+	for range 2 {
+		if got := slices.Collect(memo); !slices.Equal(got, []int{0, 1, 2, 3}) {
+			t.Errorf("got %v, want [0 1 2 3]", got)
+		}
+	}
+	if runs != 1 {
+		t.Errorf("source body ran %d times, want once", runs)
+	}
+
+	// A partial first pass is resumed, not restarted
+	runs = 0
+	memo = Memoize(source)
+	CollectN(memo, 2)
+	if got := slices.Collect(memo); !slices.Equal(got, []int{0, 1, 2, 3}) || runs != 1 {
+		t.Errorf("after a partial pass: got %v with %d runs, want [0 1 2 3] and one run", got, runs)
+	}
+}
+
+func TestOffsetIndex(t *testing.T) {
+	words := slices.All([]string{"a", "b", "c"})
+	for _, tt := range []struct {
+		start int
+		want  []int
+	}{
+		{1, []int{1, 2, 3}},
+		{10, []int{10, 11, 12}},
+		{-2, []int{-2, -1, 0}},
+		{0, []int{0, 1, 2}},
+	} {
+		keys, values := Unzip2(OffsetIndex(words, tt.start))
+		if !slices.Equal(keys, tt.want) || !slices.Equal(values, []string{"a", "b", "c"}) {
+			t.Errorf("start %d: got %v and %q, want %v and the values unchanged", tt.start, keys, values, tt.want)
+		}
+	}
+}
+
+func TestSortedByKey(t *testing.T) {
+	m := map[string]string{"Xiaomi": "China", "Apple": "United States", "Samsung": "South Korea"}
+	keys, values := Unzip2(SortedByKey(maps.All(m)))
+	if want := []string{"Apple", "Samsung", "Xiaomi"}; !slices.Equal(keys, want) {
+		t.Errorf("got keys %q, want %q", keys, want)
+	}
+	if want := []string{"United States", "South Korea", "China"}; !slices.Equal(values, want) {
+		t.Errorf("got values %q, want %q", values, want)
+	}
+
+	// Equal keys keep their order
+	pairs := pairSeq([]Pair[int, string]{{2, "x"}, {1, "y"}, {2, "z"}})
+	if _, values := Unzip2(SortedByKey(pairs)); !slices.Equal(values, []string{"y", "x", "z"}) {
+		t.Errorf("stability: got %q, want [y x z]", values)
+	}
+}
+
+func TestCollectErrors(t *testing.T) {
+	errBad := errors.New("bad")
+	values, errs := CollectErrors(pairSeq([]Pair[string, error]{{"a", nil}, {"", errBad}, {"b", nil}}))
+	if !slices.Equal(values, []string{"a", "b"}) {
+		t.Errorf("got values %q, want [a b]", values)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], errBad) {
+		t.Errorf("got errors %v, want [%v]", errs, errBad)
+	}
+}
+
+func TestFlatten2(t *testing.T) {
+	pairs := []Pair[string, int]{{"b", 1}, {"a", 2}, {"c", 3}, {"a", 4}, {"b", 5}, {"a", 6}}
+	sorted := slices.Clone(pairs)
+	slices.SortStableFunc(sorted, func(a, b Pair[string, int]) int { return strings.Compare(a.Key, b.Key) })
+	seq := pairSeq(sorted)
+
+	counts := func(pairs []Pair[string, int]) map[Pair[string, int]]int {
+		m := make(map[Pair[string, int]]int)
+		for _, p := range pairs {
+			m[p]++
+		}
+		return m
+	}
+	got := collectPairs(Flatten2(ChunkByKey(seq)))
+	if !maps.Equal(counts(got), counts(pairs)) {
+		t.Errorf("got %v, want the pairs of %v", got, pairs)
+	}
+
+	// The first group holds the three "a" pairs, so stopping at the fourth
+	// pair stops in the middle of the "b" group
+	got = nil
+	for k, v := range Flatten2(ChunkByKey(seq)) {
+		got = append(got, Pair[string, int]{k, v})
+		if len(got) == 4 {
+			break
+		}
+	}
+	if want := []Pair[string, int]{{"a", 2}, {"a", 4}, {"a", 6}, {"b", 1}}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestDrainN(t *testing.T) {
+	var (
+		n   int
+		hit bool
+	)
+	out := captureStdout(t, func() { n, hit = DrainN(NewRandomValuesGenerator(WithLimit(3)).All(), 10) })
+	if n != 3 || hit {
+		t.Errorf("DrainN of 3 pairs = %d, %t; want 3, false", n, hit)
+	}
+	if out != "Limit reached\n" {
+		t.Errorf("generator printed %q, want it drained", out)
+	}
+
+	var p probe
+	if n, hit := DrainN(probeSeq2(&p, ZipSeq(naturals(), naturals())), 5); n != 5 || !hit {
+		t.Errorf("DrainN of an infinite seq = %d, %t; want 5, true", n, hit)
+	}
+	if p.pulled != 5 || !p.stopped {
+		t.Errorf("pulled %d pairs, stopped %t; want 5 and stopped", p.pulled, p.stopped)
+	}
+}
+
+func TestSplitAt(t *testing.T) {
+	log := "2024-06-01 start\n  loading config\n2024-06-01 request\n  GET /\n  200 OK\n2024-06-02 stop\n"
+	isTimestamp := func(line string, err error) bool { return err == nil && strings.HasPrefix(line, "2024-") }
+
+	var records [][]string
+	for i, segment := range SplitAt(NewFileReader(writeTestFile(t, log)).All(), isTimestamp) {
+		if i != len(records) {
+			t.Errorf("segment %d yielded with index %d", len(records), i)
+		}
+		var lines []string
+		for _, p := range segment {
+			if p.Value != nil {
+				t.Fatal(p.Value)
+			}
+			lines = append(lines, p.Key)
+		}
+		records = append(records, lines)
+	}
+	want := [][]string{
+		{"2024-06-01 start", "  loading config"},
+		{"2024-06-01 request", "  GET /", "  200 OK"},
+		{"2024-06-02 stop"},
+	}
+	if !slices.EqualFunc(records, want, slices.Equal) {
+		t.Errorf("got %q, want %q", records, want)
+	}
+}
+
+func TestShuffle(t *testing.T) {
+	letters := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+	var (
+		indices []int
+		got     []string
+	)
+	for i, s := range Shuffle(slices.All(letters), rand.New(rand.NewPCG(1, 1))) {
+		indices = append(indices, i)
+		got = append(got, s)
+	}
+	if want := []int{3, 5, 8, 2, 7, 1, 6, 4, 0, 9}; !slices.Equal(indices, want) {
+		t.Errorf("shuffled indices = %v, want %v", indices, want)
+	}
+	for i, s := range got {
+		if s != letters[indices[i]] {
+			t.Errorf("index %d came with %q, want %q", indices[i], s, letters[indices[i]])
+		}
+	}
+}
+
+func TestFirstError(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.txt")
+	err := FirstError(NewFileReader(missing).All())
+	var fileErr *FileError
+	if !errors.As(err, &fileErr) || fileErr.Op != "open" || !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("FirstError over a missing file = %v, want its open error", err)
+	}
+
+	if err := FirstError(NewFileReader(writeTestFile(t, "a\nb\nc\n")).All()); err != nil {
+		t.Errorf("FirstError over a readable file = %v, want nil", err)
+	}
+
+	errBad := errors.New("bad line")
+	var p probe
+	seq := pairSeq([]Pair[string, error]{{"", nil}, {"", errBad}, {"", nil}, {"", errors.New("later")}})
+	if err := FirstError(probeSeq2(&p, seq)); err != errBad || p.pulled != 2 || !p.stopped {
+		t.Errorf("FirstError = %v after %d pairs, stopped %t; want %v after 2 and stopped", err, p.pulled, p.stopped, errBad)
+	}
+}
+
+func TestStepBy(t *testing.T) {
+	digits := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	if got, want := slices.Collect(StepBy(slices.Values(digits), 3)), []int{0, 3, 6, 9}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	var p probe
+	for v := range StepBy(probeSeq(&p, slices.Values(digits)), 3) {
+		if v == 3 {
+			break
+		}
+	}
+	if p.pulled != 4 || !p.stopped {
+		t.Errorf("pulled %d values, stopped %t; want the source stopped at 3", p.pulled, p.stopped)
+	}
+}
+
+func TestStepByInvalidStep(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("StepBy with step 0 did not panic")
+		}
+	}()
+	StepBy(slices.Values([]int{1}), 0)
+}
+
+func TestSample(t *testing.T) {
+	values := make([]int, 100)
+	for i := range values {
+		values[i] = i * i
+	}
+	got := Sample(slices.All(values), 5, rand.New(rand.NewPCG(1, 1)))
+	want := []Pair[int, int]{{36, 1296}, {77, 5929}, {65, 4225}, {37, 1369}, {28, 784}}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	few := []string{"a", "b", "c"}
+	got2 := Sample(slices.All(few), 5, rand.New(rand.NewPCG(1, 1)))
+	if want := []Pair[int, string]{{0, "a"}, {1, "b"}, {2, "c"}}; !slices.Equal(got2, want) {
+		t.Errorf("sampling 5 of 3 = %v, want all of %v", got2, want)
+	}
+}
+
+func TestMapKeys(t *testing.T) {
+	m := map[string]string{"Apple": "Unites States", "Samsung": "South Korea", "Xiaomi": "China"}
+	got := maps.Collect(MapKeys(maps.All(m), strings.ToLower))
+	want := map[string]string{"apple": "Unites States", "samsung": "South Korea", "xiaomi": "China"}
+	if !maps.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	n := 0
+	for range MapKeys(maps.All(m), strings.ToLower) {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Errorf("ranged %d pairs after break, want 1", n)
+	}
+}
+
+func TestSortedEntries(t *testing.T) {
+	m := map[string]int{"pear": 3, "apple": 1, "fig": 4, "banana": 2}
+	want := []Pair[string, int]{{"apple", 1}, {"banana", 2}, {"fig", 4}, {"pear", 3}}
+	// Map iteration order varies between ranges, so take several snapshots
+	for range 5 {
+		if got := SortedEntries(maps.All(m)); !slices.Equal(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestCollectN(t *testing.T) {
+	var p probe
+	if got := CollectN(probeSeq(&p, naturals()), 5); !slices.Equal(got, []int{0, 1, 2, 3, 4}) {
+		t.Errorf("got %v, want the first five values", got)
+	}
+	if p.pulled != 5 || !p.stopped {
+		t.Errorf("pulled %d values, stopped %t; want 5 and stopped", p.pulled, p.stopped)
+	}
+
+	if got := CollectN(Cycle(slices.Values([]int{1, 2})), 5); !slices.Equal(got, []int{1, 2, 1, 2, 1}) {
+		t.Errorf("CollectN of a Cycle = %v, want [1 2 1 2 1]", got)
+	}
+	if got := CollectN(slices.Values([]int{1, 2}), 5); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("CollectN of a short seq = %v, want [1 2]", got)
+	}
+	if got := CollectN(slices.Values([]int{1, 2}), math.MaxInt); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("CollectN with max math.MaxInt = %v, want [1 2]", got)
+	}
+}
+
+func TestIndexOf(t *testing.T) {
+	words := slices.All([]string{"apple", "banana", "cherry", "date"})
+	tests := []struct {
+		name   string
+		prefix string
+		want   int
+		pulled int
+	}{
+		{"first", "a", 0, 1},
+		{"middle", "c", 2, 3},
+		{"none", "z", -1, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p probe
+			got := IndexOf(probeSeq2(&p, words), func(_ int, w string) bool { return strings.HasPrefix(w, tt.prefix) })
+			if got != tt.want || p.pulled != tt.pulled {
+				t.Errorf("IndexOf = %d after %d pairs, want %d after %d", got, p.pulled, tt.want, tt.pulled)
+			}
+			if found := tt.want >= 0; p.stopped != found {
+				t.Errorf("source stopped %t, want %t", p.stopped, found)
+			}
+		})
+	}
+}
+
+func TestRunningMaxMin(t *testing.T) {
+	values := []int{3, 1, 4, 1, 5, 9, 2, 6}
+
+	keys, maxes := Unzip2(RunningMax(slices.All(values)))
+	if want := []int{3, 3, 4, 4, 5, 9, 9, 9}; !slices.Equal(maxes, want) {
+		t.Errorf("RunningMax = %v, want %v", maxes, want)
+	}
+	if !slices.Equal(keys, []int{0, 1, 2, 3, 4, 5, 6, 7}) {
+		t.Errorf("RunningMax keys = %v, want the original indices", keys)
+	}
+	if !slices.IsSorted(maxes) {
+		t.Errorf("RunningMax = %v, want non-decreasing", maxes)
+	}
+
+	_, mins := Unzip2(RunningMin(slices.All(values)))
+	if want := []int{3, 1, 1, 1, 1, 1, 1, 1}; !slices.Equal(mins, want) {
+		t.Errorf("RunningMin = %v, want %v", mins, want)
+	}
+
+	var p probe
+	for _, v := range RunningMax(probeSeq2(&p, slices.All(values))) {
+		if v == 4 {
+			break
+		}
+	}
+	if p.pulled != 3 || !p.stopped {
+		t.Errorf("early stop: pulled %d pairs, stopped %t; want 3 and stopped", p.pulled, p.stopped)
+	}
+}
+
+func TestIgnoreErrors(t *testing.T) {
+	type record struct {
+		Name string `json:"name"`
+	}
+	decode := func(line string) (record, error) {
+		var r record
+		err := json.Unmarshal([]byte(line), &r)
+		return r, err
+	}
+	// JSON lines with a malformed one in the middle
+	path := writeTestFile(t, `{"name": "a"}`+"\n"+`{"name": `+"\n"+`{"name": "c"}`+"\n")
+
+	lines := IgnoreErrors(NewFileReader(path).All())
+	got := slices.Collect(IgnoreErrors(MapErr(lines, decode)))
+	if want := []record{{"a"}, {"c"}}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	lines := []string{"first", "", "third line", "last"}
+	lineSeq, lineErr := UntilError(NewFileReader(writeTestFile(t, strings.Join(lines, "\n")+"\n")).All())
+	if got, want := Join(lineSeq, "\n"), strings.Join(lines, "\n"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if err := lineErr(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := Join(slices.Values([]string{}), ", "); got != "" {
+		t.Errorf("joining nothing = %q, want \"\"", got)
+	}
+	if got := Join(slices.Values([]string{"solo"}), ", "); got != "solo" {
+		t.Errorf("joining one string = %q, want \"solo\"", got)
+	}
+}
+
+func TestDedupErrors(t *testing.T) {
+	errA := errors.New("disk error")
+	seq := pairSeq([]Pair[int, error]{
+		{0, nil}, {1, errA}, {2, errA}, {3, errors.New("disk error")}, {4, nil}, {5, nil}, {6, errA},
+	})
+
+	keys, _ := Unzip2(DedupErrors(seq))
+	// The run at 1..3 collapses to its first pair; the nil at 4 ends it, so
+	// errA shows up again at 6
+	if want := []int{0, 1, 4, 5, 6}; !slices.Equal(keys, want) {
+		t.Errorf("got keys %v, want %v", keys, want)
+	}
+}
+
+func TestAssertValues(t *testing.T) {
+	seq := slices.All([]any{1, "two", 3, 4.0, 5})
+
+	ints, intErr := AssertValues[int, int](seq, SkipMismatches)
+	keys, values := Unzip2(ints)
+	if !slices.Equal(keys, []int{0, 2, 4}) || !slices.Equal(values, []int{1, 3, 5}) {
+		t.Errorf("SkipMismatches yielded keys %v and values %v, want [0 2 4] and [1 3 5]", keys, values)
+	}
+	if err := intErr(); err != nil {
+		t.Errorf("SkipMismatches error = %v, want nil", err)
+	}
+
+	ints, intErr = AssertValues[int, int](seq, RejectMismatches)
+	if _, values := Unzip2(ints); !slices.Equal(values, []int{1}) {
+		t.Errorf("RejectMismatches yielded %v, want [1]", values)
+	}
+	if err := intErr(); err == nil || !strings.Contains(err.Error(), "key 1") {
+		t.Errorf("RejectMismatches error = %v, want one naming key 1", err)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	var reports []int
+	got := slices.Collect(Progress(slices.Values([]int{0, 1, 2, 3, 4}), 2, func(count int) {
+		reports = append(reports, count)
+	}))
+	if !slices.Equal(got, []int{0, 1, 2, 3, 4}) {
+		t.Errorf("got %v, want the values unchanged", got)
+	}
+	if want := []int{2, 4}; !slices.Equal(reports, want) {
+		t.Errorf("reported %v, want %v", reports, want)
+	}
+}
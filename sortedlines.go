@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"iter"
+	"os"
+	"slices"
+)
+
+// maxSpills caps how many spill files SortedLines merges at once
+const maxSpills = 16
+
+// SortedLines returns the lines of the file in sorted order, or an error in
+// place of a line. It is an external merge sort: lines are gathered in chunks
+// of roughly maxMem bytes, each chunk is sorted and spilled to a temporary
+// file, and the spilled files are merged while iterating. To bound the open
+// files, every maxSpills spills are first merged into one. A file that fits
+// in a single chunk is sorted in memory. Line endings are never kept, whatever
+// the reader's options. The temporary files are removed when the range ends,
+// early or not. Lines are paired with an error, as with All, rather than
+// with their position in sorted order: reading, spilling and merging can
+// each fail part way, and a position is easily counted while ranging
+func (r FileReader) SortedLines(maxMem int) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		if maxMem <= 0 {
+			yield("", &FileError{Path: r.file, Op: "sort", Err: fmt.Errorf("invalid maxMem %d", maxMem)})
+			return
+		}
+		file, err := os.Open(r.file)
+		if err != nil {
+			yield("", &FileError{Path: r.file, Op: "open", Err: err})
+			return
+		}
+		defer file.Close()
+
+		var (
+			chunk  []string
+			size   int
+			spills []*os.File
+		)
+		defer func() {
+			for _, f := range spills {
+				removeSpill(f)
+			}
+		}()
+		spill := func() error {
+			slices.Sort(chunk)
+			f, err := writeSpill(func(yield func(string, error) bool) {
+				for _, line := range chunk {
+					if !yield(line, nil) {
+						return
+					}
+				}
+			})
+			if err != nil {
+				return err
+			}
+			spills = append(spills, f)
+			chunk, size = chunk[:0], 0
+			if len(spills) < maxSpills {
+				return nil
+			}
+			merged, err := writeSpill(mergeSpills(spills))
+			if err != nil {
+				return err
+			}
+			for _, f := range spills {
+				removeSpill(f)
+			}
+			spills = append(spills[:0], merged)
+			return nil
+		}
+
+		for line, err := range readerSeq(file, lineConfig{}) {
+			if err != nil {
+				yield("", &FileError{Path: r.file, Op: "read", Err: err})
+				return
+			}
+			chunk = append(chunk, line)
+			size += len(line)
+			if size >= maxMem {
+				if err := spill(); err != nil {
+					yield("", err)
+					return
+				}
+			}
+		}
+
+		if len(spills) == 0 {
+			slices.Sort(chunk)
+			for _, line := range chunk {
+				if !yield(line, nil) {
+					return
+				}
+			}
+			return
+		}
+		if len(chunk) > 0 {
+			if err := spill(); err != nil {
+				yield("", err)
+				return
+			}
+		}
+		mergeSpills(spills)(yield)
+	}
+}
+
+// writeSpill writes lines to a new temporary file, rewound for reading.
+// The file is removed again if writing fails
+func writeSpill(lines iter.Seq2[string, error]) (*os.File, error) {
+	f, err := os.CreateTemp("", "sortedlines-*")
+	if err != nil {
+		return nil, fmt.Errorf("create spill file: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	for line, err := range lines {
+		if err == nil {
+			_, err = w.WriteString(line + "\n")
+			if err != nil {
+				err = fmt.Errorf("write spill file: %w", err)
+			}
+		}
+		if err != nil {
+			removeSpill(f)
+			return nil, err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		removeSpill(f)
+		return nil, fmt.Errorf("write spill file: %w", err)
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		removeSpill(f)
+		return nil, fmt.Errorf("rewind spill file: %w", err)
+	}
+	return f, nil
+}
+
+func removeSpill(f *os.File) {
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// mergeSpills merges the sorted spill files into one sorted sequence, reading
+// each from its current offset
+func mergeSpills(spills []*os.File) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		h := make(mergeHeap, 0, len(spills))
+		for _, f := range spills {
+			next, stop := iter.Pull2(readerSeq(f, lineConfig{}))
+			defer stop()
+			line, err, ok := next()
+			if err != nil {
+				yield("", fmt.Errorf("read spill file: %w", err))
+				return
+			}
+			if ok {
+				h = append(h, mergeSource{line: line, next: next})
+			}
+		}
+		heap.Init(&h)
+		for len(h) > 0 {
+			top := &h[0]
+			if !yield(top.line, nil) {
+				return
+			}
+			line, err, ok := top.next()
+			if err != nil {
+				yield("", fmt.Errorf("read spill file: %w", err))
+				return
+			}
+			if ok {
+				top.line = line
+				heap.Fix(&h, 0)
+			} else {
+				heap.Pop(&h)
+			}
+		}
+	}
+}
+
+// mergeHeap holds the current line of every spill file, smallest on top
+type mergeHeap []mergeSource
+
+type mergeSource struct {
+	line string
+	next func() (string, error, bool)
+}
+
+func (h mergeHeap) Len() int           { return len(h) }
+func (h mergeHeap) Less(i, j int) bool { return h[i].line < h[j].line }
+func (h mergeHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x any)        { *h = append(*h, x.(mergeSource)) }
+func (h *mergeHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// shuffledLines returns n distinct sorted lines and a file holding them in a
+// seeded random order
+func shuffledLines(t *testing.T, n int) (sorted []string, path string) {
+	t.Helper()
+	for i := range n {
+		sorted = append(sorted, fmt.Sprintf("line %04d", i))
+	}
+	shuffled := slices.Clone(sorted)
+	r := rand.New(rand.NewPCG(1, 1))
+	r.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+	return sorted, writeTestFile(t, strings.Join(shuffled, "\n")+"\n")
+}
+
+// spillDir points os.CreateTemp at a fresh directory and returns it, so the
+// test can check the spill files are gone
+func spillDir(t *testing.T) string {
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+	return dir
+}
+
+func checkNoSpills(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) > 0 {
+		t.Errorf("%d spill files left behind", len(entries))
+	}
+}
+
+func TestSortedLines(t *testing.T) {
+	sorted, path := shuffledLines(t, 500)
+	tests := []struct {
+		name   string
+		maxMem int
+		opts   []LineOption
+	}{
+		{"in memory", 1 << 20, nil},
+		{"a few spills", 1000, nil},
+		// 9 bytes per line, a spill every line: far more than maxSpills
+		{"one line per spill", 1, nil},
+		{"line endings ignored", 100, []LineOption{KeepLineEndings()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := spillDir(t)
+			got := collectLines(t, NewFileReader(path, tt.opts...).SortedLines(tt.maxMem))
+			if !slices.Equal(got, sorted) {
+				t.Errorf("got %d lines, want the %d lines sorted", len(got), len(sorted))
+			}
+			checkNoSpills(t, dir)
+		})
+	}
+}
+
+func TestSortedLinesEarlyStop(t *testing.T) {
+	sorted, path := shuffledLines(t, 200)
+	dir := spillDir(t)
+
+	var got []string
+	for line, err := range NewFileReader(path).SortedLines(50) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, line)
+		if len(got) == 3 {
+			break
+		}
+	}
+	if !slices.Equal(got, sorted[:3]) {
+		t.Errorf("got %q, want %q", got, sorted[:3])
+	}
+	checkNoSpills(t, dir)
+}
+
+func TestSortedLinesErrors(t *testing.T) {
+	_, path := shuffledLines(t, 10)
+	for _, maxMem := range []int{0, -1} {
+		var errs []error
+		for _, err := range NewFileReader(path).SortedLines(maxMem) {
+			errs = append(errs, err)
+		}
+		if len(errs) != 1 || errs[0] == nil {
+			t.Errorf("maxMem %d: got %v, want a single error", maxMem, errs)
+		}
+	}
+}
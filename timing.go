@@ -0,0 +1,235 @@
+package main
+
+import (
+	"errors"
+	"iter"
+	"time"
+)
+
+// Throttle passes seq through, making sure at least interval elapses between
+// successive values handed to the consumer
+func Throttle[V any](seq iter.Seq[V], interval time.Duration) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		var timer *time.Timer
+		defer func() {
+			if timer != nil {
+				timer.Stop()
+			}
+		}()
+
+		for v := range seq {
+			if timer == nil {
+				timer = time.NewTimer(interval)
+			} else {
+				<-timer.C
+				timer.Reset(interval)
+			}
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Clock tells the current time. Time-based combinators take one so they can
+// be driven by a fake clock instead of the wall clock
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the wall clock
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Debounce2 collapses bursts of seq: a pair is yielded only if no newer pair
+// arrives within quiet after it. Arrival times are taken when the source
+// produces a pair, so the decision for a pair is made when the next one
+// arrives. At the end of the stream the pending pair is always flushed
+func Debounce2[K, V any](seq iter.Seq2[K, V], quiet time.Duration) iter.Seq2[K, V] {
+	return Debounce2WithClock(seq, quiet, SystemClock)
+}
+
+// Debounce2WithClock is Debounce2 reading arrival times from clock
+func Debounce2WithClock[K, V any](seq iter.Seq2[K, V], quiet time.Duration, clock Clock) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		var (
+			pendingK K
+			pendingV V
+			pending  bool
+			at       time.Time
+		)
+		for k, v := range seq {
+			now := clock.Now()
+			if pending && now.Sub(at) >= quiet {
+				if !yield(pendingK, pendingV) {
+					return
+				}
+			}
+			pendingK, pendingV, pending, at = k, v, true, now
+		}
+		if pending {
+			yield(pendingK, pendingV)
+		}
+	}
+}
+
+// ErrTimeout is reported by WithTimeout when its deadline cut the sequence
+// short
+var ErrTimeout = errors.New("iteration timed out")
+
+// WithTimeout passes seq through until d has elapsed since the first pair was
+// yielded, then stops seq. The returned function reports ErrTimeout if that
+// is how the last range ended, or nil if it ended with the source or the
+// consumer. The deadline is only checked between pairs: a source that blocks
+// while producing a pair can't be interrupted and delays the stop
+func WithTimeout[K, V any](seq iter.Seq2[K, V], d time.Duration) (iter.Seq2[K, V], func() error) {
+	return WithTimeoutWithClock(seq, d, SystemClock)
+}
+
+// WithTimeoutWithClock is WithTimeout measuring time with clock
+func WithTimeoutWithClock[K, V any](seq iter.Seq2[K, V], d time.Duration, clock Clock) (iter.Seq2[K, V], func() error) {
+	var lastErr error
+	pairs := func(yield func(K, V) bool) {
+		lastErr = nil
+		var start time.Time
+		first := true
+		for k, v := range seq {
+			if first {
+				start, first = clock.Now(), false
+			} else if clock.Now().Sub(start) >= d {
+				lastErr = ErrTimeout
+				return
+			}
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+	return pairs, func() error { return lastErr }
+}
+
+// Debounce collapses bursts of seq to their latest value: a value is yielded
+// once quiet has elapsed without the source producing a newer one. Unlike
+// Debounce2, which judges a pair when the next one arrives, this ranges seq
+// in a goroutine and yields on a timer, so a value goes out as soon as its
+// quiet period is over. At the end of the stream the pending value is
+// flushed. Stopping early stops the goroutine and waits for it
+func Debounce[V any](seq iter.Seq[V], quiet time.Duration) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		values := make(chan V)
+		done := make(chan struct{})
+		go func() {
+			defer close(values)
+			for v := range seq {
+				select {
+				case values <- v:
+				case <-done:
+					return
+				}
+			}
+		}()
+		defer func() {
+			close(done)
+			for range values {
+			}
+		}()
+
+		timer := time.NewTimer(quiet)
+		timer.Stop()
+		defer timer.Stop()
+		var (
+			pending V
+			has     bool
+		)
+		for {
+			select {
+			case v, ok := <-values:
+				if !ok {
+					if has {
+						yield(pending)
+					}
+					return
+				}
+				pending, has = v, true
+				timer.Reset(quiet)
+			case <-timer.C:
+				if has {
+					has = false
+					if !yield(pending) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// ChunkTimeout batches the pairs of seq, flushing a batch once it holds n
+// pairs or d has elapsed since its first pair arrived, whichever comes
+// first. Time is checked as pairs arrive, so a batch that runs out of time
+// goes out when the next pair shows up, which starts the following batch.
+// A final partial batch is flushed at the end of the stream
+func ChunkTimeout[K, V any](seq iter.Seq2[K, V], n int, d time.Duration) iter.Seq[[]Pair[K, V]] {
+	return ChunkTimeoutWithClock(seq, n, d, SystemClock)
+}
+
+// ChunkTimeoutWithClock is ChunkTimeout measuring time with clock
+func ChunkTimeoutWithClock[K, V any](seq iter.Seq2[K, V], n int, d time.Duration, clock Clock) iter.Seq[[]Pair[K, V]] {
+	return func(yield func([]Pair[K, V]) bool) {
+		var (
+			batch []Pair[K, V]
+			start time.Time
+		)
+		for k, v := range seq {
+			now := clock.Now()
+			if len(batch) > 0 && now.Sub(start) >= d {
+				if !yield(batch) {
+					return
+				}
+				batch = nil
+			}
+			if len(batch) == 0 {
+				start = now
+			}
+			batch = append(batch, Pair[K, V]{k, v})
+			if len(batch) >= n {
+				if !yield(batch) {
+					return
+				}
+				batch = nil
+			}
+		}
+		if len(batch) > 0 {
+			yield(batch)
+		}
+	}
+}
+
+// WithPerElementTimeout passes seq through unchanged and calls onSlow for
+// every pair that took the source longer than d to produce, before handing
+// it on. The time is measured from the start of the range, or from when the
+// consumer handed control back after the previous pair, to the pair's arrival
+func WithPerElementTimeout[K, V any](seq iter.Seq2[K, V], d time.Duration, onSlow func(K, V)) iter.Seq2[K, V] {
+	return WithPerElementTimeoutWithClock(seq, d, onSlow, SystemClock)
+}
+
+// WithPerElementTimeoutWithClock is WithPerElementTimeout measuring time with clock
+func WithPerElementTimeoutWithClock[K, V any](seq iter.Seq2[K, V], d time.Duration, onSlow func(K, V), clock Clock) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		since := clock.Now()
+		for k, v := range seq {
+			if clock.Now().Sub(since) > d {
+				onSlow(k, v)
+			}
+			if !yield(k, v) {
+				return
+			}
+			since = clock.Now()
+		}
+	}
+}
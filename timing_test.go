@@ -0,0 +1,221 @@
+package main
+
+import (
+	"errors"
+	"iter"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	const (
+		n        = 5
+		interval = 10 * time.Millisecond
+	)
+	start := time.Now()
+	var got []int
+	for v := range Throttle(slices.Values([]int{0, 1, 2, 3, 4}), interval) {
+		got = append(got, v)
+	}
+	elapsed := time.Since(start)
+
+	if !slices.Equal(got, []int{0, 1, 2, 3, 4}) {
+		t.Errorf("got %v, want the values unchanged", got)
+	}
+	if min := (n - 1) * interval; elapsed < min {
+		t.Errorf("%d values took %v, want at least %v", n, elapsed, min)
+	}
+}
+
+func TestThrottleEarlyStop(t *testing.T) {
+	count := 0
+	for range Throttle(slices.Values([]int{0, 1, 2, 3, 4}), time.Millisecond) {
+		count++
+		if count == 2 {
+			break
+		}
+	}
+	if count != 2 {
+		t.Errorf("got %d values, want 2", count)
+	}
+}
+
+// fakeClock is a Clock that only moves when told to
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// timed yields the values with their index, advancing clock by the matching
+// gap before each one
+func timed[V any](clock *fakeClock, gaps []time.Duration, values []V) iter.Seq2[int, V] {
+	return func(yield func(int, V) bool) {
+		for i, v := range values {
+			clock.Advance(gaps[i])
+			if !yield(i, v) {
+				return
+			}
+		}
+	}
+}
+
+func TestDebounce2(t *testing.T) {
+	ms := time.Millisecond
+	clock := &fakeClock{}
+	// A burst of three, a gap, then a burst of two
+	source := timed(clock, []time.Duration{0, ms, ms, 100 * ms, ms}, []string{"a", "b", "c", "d", "e"})
+
+	var got []string
+	for _, v := range Debounce2WithClock(source, 10*ms, clock) {
+		got = append(got, v)
+	}
+	if want := []string{"c", "e"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestDebounce2SpacedValues(t *testing.T) {
+	ms := time.Millisecond
+	clock := &fakeClock{}
+	source := timed(clock, []time.Duration{0, 20 * ms, 20 * ms}, []int{1, 2, 3})
+
+	var got []int
+	for _, v := range Debounce2WithClock(source, 10*ms, clock) {
+		got = append(got, v)
+	}
+	if want := []int{1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	ms := time.Millisecond
+	clock := &fakeClock{}
+	// The deadline counts from the first pair: "b" arrives 10ms into the range
+	// but only 5ms after "a"
+	source := timed(clock, []time.Duration{5 * ms, 5 * ms, 5 * ms, 5 * ms}, []string{"a", "b", "c", "d"})
+
+	pairs, timeoutErr := WithTimeoutWithClock(source, 8*ms, clock)
+	var got []string
+	for _, v := range pairs {
+		got = append(got, v)
+	}
+	if want := []string{"a", "b"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if elapsed := clock.now.Sub(time.Time{}); elapsed != 15*ms {
+		t.Errorf("source ran for %v, want it stopped at the third pair", elapsed)
+	}
+	if err := timeoutErr(); !errors.Is(err, ErrTimeout) {
+		t.Errorf("error = %v, want ErrTimeout", err)
+	}
+}
+
+func TestWithTimeoutInTime(t *testing.T) {
+	ms := time.Millisecond
+	clock := &fakeClock{}
+	pairs, timeoutErr := WithTimeoutWithClock(timed(clock, []time.Duration{ms, ms}, []int{1, 2}), 8*ms, clock)
+	n := 0
+	for range pairs {
+		n++
+	}
+	if n != 2 {
+		t.Errorf("got %d pairs, want both", n)
+	}
+	if err := timeoutErr(); err != nil {
+		t.Errorf("error after the source ended = %v, want nil", err)
+	}
+}
+
+func TestDebounce(t *testing.T) {
+	const quiet = 50 * time.Millisecond
+	// Two bursts of values a millisecond apart, with a gap of several quiet
+	// periods in between
+	bursts := [][]string{{"a", "b", "c"}, {"d", "e"}}
+	source := func(yield func(string) bool) {
+		for i, burst := range bursts {
+			if i > 0 {
+				time.Sleep(4 * quiet)
+			}
+			for _, v := range burst {
+				if !yield(v) {
+					return
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}
+
+	got := slices.Collect(Debounce(iter.Seq[string](source), quiet))
+	if want := []string{"c", "e"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestDebounceEarlyStop(t *testing.T) {
+	before := runtime.NumGoroutine()
+	source := func(yield func(int) bool) {
+		for i := 0; ; i++ {
+			if !yield(i) {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	for v := range Debounce(iter.Seq[int](source), 5*time.Millisecond) {
+		if v == 2 {
+			break
+		}
+	}
+	checkNoLeak(t, before)
+}
+
+func TestChunkTimeout(t *testing.T) {
+	ms := time.Millisecond
+	clock := &fakeClock{}
+	// "a" to "c" fill a batch; "d" runs out of time before "e" arrives, and
+	// "e" and "f" are left over at the end
+	source := timed(clock, []time.Duration{0, ms, ms, 5 * ms, 20 * ms, ms}, []string{"a", "b", "c", "d", "e", "f"})
+
+	var got [][]string
+	for batch := range ChunkTimeoutWithClock(source, 3, 10*ms, clock) {
+		var values []string
+		for _, p := range batch {
+			values = append(values, p.Value)
+		}
+		got = append(got, values)
+	}
+	want := [][]string{{"a", "b", "c"}, {"d"}, {"e", "f"}}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestWithPerElementTimeout(t *testing.T) {
+	ms := time.Millisecond
+	clock := &fakeClock{}
+	source := timed(clock, []time.Duration{ms, ms, 20 * ms, ms}, []string{"a", "b", "c", "d"})
+
+	var slow []string
+	var got []string
+	for _, v := range WithPerElementTimeoutWithClock(source, 10*ms, func(_ int, v string) { slow = append(slow, v) }, clock) {
+		got = append(got, v)
+		// A slow consumer doesn't count against the source
+		clock.Advance(50 * ms)
+	}
+	if !slices.Equal(got, []string{"a", "b", "c", "d"}) {
+		t.Errorf("got %q, want the values unchanged", got)
+	}
+	if want := []string{"c"}; !slices.Equal(slow, want) {
+		t.Errorf("onSlow called for %q, want %q", slow, want)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"reflect"
+	"text/tabwriter"
+)
+
+type FileWriter struct {
+	file string
+}
+
+func NewFileWriter(file string) FileWriter {
+	return FileWriter{file: file}
+}
+
+// WriteAll writes every line of seq followed by "\n" to the file, creating
+// or truncating it. It stops at the first error; the file is flushed and
+// closed in any case
+func (w FileWriter) WriteAll(seq iter.Seq2[int, string]) (err error) {
+	file, err := os.Create(w.file)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			err = errors.Join(err, fmt.Errorf("close: %w", closeErr))
+		}
+	}()
+
+	writer := bufio.NewWriter(file)
+	defer func() {
+		// bufio.Writer errors are sticky, so a failed write is already reported
+		if flushErr := writer.Flush(); flushErr != nil && err == nil {
+			err = fmt.Errorf("flush: %w", flushErr)
+		}
+	}()
+
+	for _, line := range seq {
+		if _, err := writer.WriteString(line + "\n"); err != nil {
+			return fmt.Errorf("write: %w", err)
+		}
+	}
+	return nil
+}
+
+// jsonEntry is how EncodeJSON encodes a pair whose key is not a string
+type jsonEntry[K, V any] struct {
+	Key   K `json:"key"`
+	Value V `json:"value"`
+}
+
+// EncodeJSON streams seq to w, one pair at a time, without holding the
+// sequence in memory. The key type decides the shape: a string kind (string
+// or a named string type) gives a JSON object mapping every key to its value,
+// any other key type an array of {"key": ..., "value": ...} objects. Values
+// are written by a json.Encoder on w, which ends each one with a newline, so
+// every element sits on a line of its own, led by the comma separating it
+// from the previous one. The first encoding or write error stops seq and is
+// returned
+func EncodeJSON[K comparable, V any](w io.Writer, seq iter.Seq2[K, V]) error {
+	object := reflect.TypeFor[K]().Kind() == reflect.String
+	open, end := "[", "]"
+	if object {
+		open, end = "{", "}"
+	}
+
+	if _, err := io.WriteString(w, open); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	enc := json.NewEncoder(w)
+	first := true
+	for k, v := range seq {
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return fmt.Errorf("write: %w", err)
+			}
+		}
+		first = false
+
+		if object {
+			key, err := json.Marshal(reflect.ValueOf(k).String())
+			if err != nil {
+				return fmt.Errorf("encode key: %w", err)
+			}
+			if _, err := w.Write(append(key, ':')); err != nil {
+				return fmt.Errorf("write: %w", err)
+			}
+			if err := enc.Encode(v); err != nil {
+				return fmt.Errorf("encode value: %w", err)
+			}
+		} else if err := enc.Encode(jsonEntry[K, V]{Key: k, Value: v}); err != nil {
+			return fmt.Errorf("encode: %w", err)
+		}
+	}
+	if _, err := io.WriteString(w, end); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	return nil
+}
+
+// EncodeCSV writes seq to w as CSV rows of two fields, key and value, both
+// formatted with fmt.Sprint. A non-nil header is written as the first row.
+// The first write error stops seq and is returned
+func EncodeCSV[K, V any](w io.Writer, seq iter.Seq2[K, V], header []string) error {
+	writer := csv.NewWriter(w)
+	if header != nil {
+		if err := writer.Write(header); err != nil {
+			return fmt.Errorf("write header: %w", err)
+		}
+	}
+	for k, v := range seq {
+		if err := writer.Write([]string{fmt.Sprint(k), fmt.Sprint(v)}); err != nil {
+			return fmt.Errorf("write: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+	return nil
+}
+
+// WriteLines writes every line of seq followed by "\n" to w and returns how
+// many lines were written. It stops at the first error, whether yielded by
+// seq or returned by w. Writes go straight to w; wrap it in a bufio.Writer
+// when that matters
+func WriteLines(w io.Writer, seq iter.Seq2[string, error]) (written int, err error) {
+	for line, err := range seq {
+		if err != nil {
+			return written, err
+		}
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return written, fmt.Errorf("write: %w", err)
+		}
+		written++
+	}
+	return written, nil
+}
+
+// WriteCSV writes every record of rows to w as CSV separated by comma and
+// returns how many records were written. It stops at the first error yielded
+// by rows; whatever was written before it is still flushed
+func WriteCSV(w io.Writer, rows iter.Seq2[[]string, error], comma rune) (int, error) {
+	writer := csv.NewWriter(w)
+	writer.Comma = comma
+
+	written := 0
+	var srcErr error
+	for record, err := range rows {
+		if err != nil {
+			srcErr = err
+			break
+		}
+		if err := writer.Write(record); err != nil {
+			return written, fmt.Errorf("write: %w", err)
+		}
+		written++
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return written, fmt.Errorf("flush: %w", err)
+	}
+	return written, srcErr
+}
+
+// Fprint writes the pairs of seq to w as "key: value", separated by sep.
+// Nothing follows the last pair
+func Fprint[K, V any](w io.Writer, seq iter.Seq2[K, V], sep string) {
+	first := true
+	for k, v := range seq {
+		if !first {
+			io.WriteString(w, sep)
+		}
+		first = false
+		fmt.Fprintf(w, "%v: %v", k, v)
+	}
+}
+
+// TeeWriter passes seq through unchanged while writing format(k, v) to w for
+// every pair, before handing it on. Write errors are ignored; use
+// TeeWriterStrict to stop at them
+func TeeWriter[K, V any](seq iter.Seq2[K, V], w io.Writer, format func(K, V) string) iter.Seq2[K, V] {
+	return Tap2(seq, func(k K, v V) {
+		io.WriteString(w, format(k, v))
+	})
+}
+
+// TeeWriterStrict is TeeWriter that stops seq at the first write error,
+// without handing on the pair that failed to write
+func TeeWriterStrict[K, V any](seq iter.Seq2[K, V], w io.Writer, format func(K, V) string) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for k, v := range seq {
+			if _, err := io.WriteString(w, format(k, v)); err != nil {
+				return
+			}
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// PrintTable writes the pairs of seq to w as two aligned columns, key and
+// value, one pair per row
+func PrintTable[K, V any](w io.Writer, seq iter.Seq2[K, V]) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for k, v := range seq {
+		fmt.Fprintf(tw, "%v\t%v\n", k, v)
+	}
+	tw.Flush()
+}
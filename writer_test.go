@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFileWriterRoundTrip(t *testing.T) {
+	in := writeTestFile(t, "alpha\nbeta\ngamma\n")
+	out := filepath.Join(t.TempDir(), "output.txt")
+
+	upper := func(yield func(int, string) bool) {
+		i := 0
+		for line, err := range NewFileReader(in).All() {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !yield(i, strings.ToUpper(line)) {
+				return
+			}
+			i++
+		}
+	}
+	if err := NewFileWriter(out).WriteAll(upper); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "ALPHA\nBETA\nGAMMA\n"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFileWriterCreateError(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "missing", "output.txt")
+	var seq iter.Seq2[int, string] = func(func(int, string) bool) {}
+	if err := NewFileWriter(out).WriteAll(seq); err == nil {
+		t.Error("WriteAll into a missing directory succeeded")
+	}
+}
+
+func TestEncodeJSON(t *testing.T) {
+	type country string
+
+	var b bytes.Buffer
+	if err := EncodeJSON(&b, slices.All([]string{"a", "b"})); err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"key":0,"value":"a"}` + "\n" + `,{"key":1,"value":"b"}` + "\n]"
+	if b.String() != want {
+		t.Errorf("int keys: got %s, want %s", b.String(), want)
+	}
+	if !json.Valid(b.Bytes()) {
+		t.Errorf("int keys: %s is not valid JSON", b.String())
+	}
+
+	b.Reset()
+	m := map[string][]int{"x": {1, 2}, "y": nil}
+	if err := EncodeJSON(&b, SortedByKey(maps.All(m))); err != nil {
+		t.Fatal(err)
+	}
+	want = `{"x":[1,2]` + "\n" + `,"y":null` + "\n}"
+	if b.String() != want {
+		t.Errorf("string keys: got %s, want %s", b.String(), want)
+	}
+	if !json.Valid(b.Bytes()) {
+		t.Errorf("string keys: %s is not valid JSON", b.String())
+	}
+
+	b.Reset()
+	named := func(yield func(country, int) bool) { yield("Apple\"", 1) }
+	if err := EncodeJSON(&b, named); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"Apple\"":1` + "\n}"; b.String() != want {
+		t.Errorf("named string keys: got %s, want %s", b.String(), want)
+	}
+
+	b.Reset()
+	if err := EncodeJSON(&b, slices.All([]int(nil))); err != nil || b.String() != "[]" {
+		t.Errorf("empty: got %s, %v; want []", b.String(), err)
+	}
+}
+
+func TestEncodeJSONError(t *testing.T) {
+	pulled := 0
+	seq := func(yield func(string, any) bool) {
+		for _, v := range []any{1, func() {}, 3} {
+			pulled++
+			if !yield("k", v) {
+				return
+			}
+		}
+	}
+	var b bytes.Buffer
+	if err := EncodeJSON(&b, seq); err == nil {
+		t.Error("encoding a func succeeded")
+	}
+	if pulled != 2 {
+		t.Errorf("pulled %d values, want the sequence stopped at the second", pulled)
+	}
+}
+
+func TestEncodeCSV(t *testing.T) {
+	values := []any{3, 1.5, true}
+	seq := func(yield func(string, any) bool) {
+		for i, name := range []string{"apple", "pear, green", `say "hi"`} {
+			if !yield(name, values[i]) {
+				return
+			}
+		}
+	}
+
+	var b bytes.Buffer
+	if err := EncodeCSV(&b, seq, []string{"name", "value"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "name,value\napple,3\n\"pear, green\",1.5\n\"say \"\"hi\"\"\",true\n"
+	if b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+
+	b.Reset()
+	if err := EncodeCSV(&b, seq, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != strings.TrimPrefix(want, "name,value\n") {
+		t.Errorf("without header: got %q", got)
+	}
+}
+
+// failWriter fails every write with err
+type failWriter struct {
+	err error
+}
+
+func (w failWriter) Write([]byte) (int, error) {
+	return 0, w.err
+}
+
+func TestEncodeCSVWriteError(t *testing.T) {
+	errDisk := errors.New("disk full")
+	err := EncodeCSV(failWriter{errDisk}, slices.All([]int{1, 2, 3}), nil)
+	if !errors.Is(err, errDisk) {
+		t.Errorf("got %v, want %v", err, errDisk)
+	}
+}
+
+// upper uppercases the lines of seq, passing errors through
+func upper(seq iter.Seq2[string, error]) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for line, err := range seq {
+			if !yield(strings.ToUpper(line), err) {
+				return
+			}
+		}
+	}
+}
+
+func TestWriteLines(t *testing.T) {
+	r := NewFileReader(writeTestFile(t, "one\ntwo\nthree"))
+	var b bytes.Buffer
+	n, err := WriteLines(&b, upper(r.All()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "ONE\nTWO\nTHREE\n"; n != 3 || b.String() != want {
+		t.Errorf("wrote %d lines %q, want 3 lines %q", n, b.String(), want)
+	}
+}
+
+func TestWriteLinesErrors(t *testing.T) {
+	errBad := errors.New("bad line")
+	seq := pairSeq([]Pair[string, error]{{"ok", nil}, {"", errBad}, {"never", nil}})
+	var b bytes.Buffer
+	n, err := WriteLines(&b, seq)
+	if n != 1 || !errors.Is(err, errBad) || b.String() != "ok\n" {
+		t.Errorf("source error: got %d, %v, %q; want 1, %v, \"ok\\n\"", n, err, b.String(), errBad)
+	}
+
+	errDisk := errors.New("disk full")
+	n, err = WriteLines(failWriter{errDisk}, NewStringReader("a").All())
+	if n != 0 || !errors.Is(err, errDisk) {
+		t.Errorf("write error: got %d, %v; want 0, %v", n, err, errDisk)
+	}
+}
+
+func TestWriteCSVRoundTrip(t *testing.T) {
+	content := "name,country\nApple,United States\n\"Acme, Inc.\",\"say \"\"hi\"\"\"\n"
+	r := NewCSVReader(writeTestFile(t, content))
+
+	var b bytes.Buffer
+	n, err := WriteCSV(&b, r.All(), ',')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 || b.String() != content {
+		t.Errorf("wrote %d records %q, want 3 records %q", n, b.String(), content)
+	}
+}
+
+func TestWriteCSVSourceError(t *testing.T) {
+	// The second record has too few fields
+	r := NewCSVReader(writeTestFile(t, "a,b\nc\nd,e\n"))
+	var b bytes.Buffer
+	n, err := WriteCSV(&b, r.All(), ';')
+	var fileErr *FileError
+	if n != 1 || !errors.As(err, &fileErr) || b.String() != "a;b\n" {
+		t.Errorf("got %d, %v, %q; want 1 record flushed before the read error", n, err, b.String())
+	}
+}
+
+func ExampleFprint() {
+	Fprint(os.Stdout, slices.All([]string{"a", "b", "c"}), "; ")
+	fmt.Println()
+	Fprint(os.Stdout, slices.All([]string(nil)), "; ")
+	fmt.Println("(empty)")
+	// Output:
+	// 0: a; 1: b; 2: c
+	// (empty)
+}
+
+func TestTeeWriter(t *testing.T) {
+	words := []string{"a", "b", "c"}
+	format := func(i int, s string) string { return fmt.Sprintf("%d=%s\n", i, s) }
+
+	var buf bytes.Buffer
+	var got []string
+	for i, s := range TeeWriter(slices.All(words), &buf, format) {
+		got = append(got, fmt.Sprint(i, s))
+	}
+	if want := []string{"0a", "1b", "2c"}; !slices.Equal(got, want) {
+		t.Errorf("passed through %q, want %q", got, want)
+	}
+	if want := "0=a\n1=b\n2=c\n"; buf.String() != want {
+		t.Errorf("wrote %q, want %q", buf.String(), want)
+	}
+
+	n := 0
+	for range TeeWriter(slices.All(words), failWriter{errors.New("closed")}, format) {
+		n++
+	}
+	if n != len(words) {
+		t.Errorf("TeeWriter passed %d pairs through a failing writer, want %d", n, len(words))
+	}
+	for range TeeWriterStrict(slices.All(words), failWriter{errors.New("closed")}, format) {
+		t.Error("TeeWriterStrict passed a pair it failed to write")
+	}
+}
+
+func TestPrintTable(t *testing.T) {
+	m := map[string]string{"Apple": "Unites States", "Samsung": "South Korea", "Xiaomi": "China"}
+	var buf bytes.Buffer
+	PrintTable(&buf, SortedByKey(maps.All(m)))
+	want := "Apple    Unites States\n" +
+		"Samsung  South Korea\n" +
+		"Xiaomi   China\n"
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}